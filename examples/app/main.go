@@ -5,6 +5,7 @@ import (
 	"os"
 
 	gen1 "github.com/rubemlrm/go-shelly/shelly/gen1"
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
 	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
 )
 
@@ -12,7 +13,7 @@ func main() {
 	opts := transport.ClientOptions{
 		Hostname: os.Getenv("HOST"),
 		Username: os.Getenv("USERNAME"),
-		Password: os.Getenv("PASSWORD"),
+		Password: contracts.Secret(os.Getenv("PASSWORD")),
 	}
 	client, err := gen1.NewRestClientWithAuth(opts)
 	if err != nil {
@@ -9,10 +9,16 @@ require (
 	github.com/stretchr/testify v1.8.4
 )
 
-require github.com/hashicorp/go-cleanhttp v0.5.2
+require (
+	github.com/gorilla/websocket v1.5.1
+	github.com/hashicorp/go-cleanhttp v0.5.2
+	go.uber.org/goleak v1.3.0
+	golang.org/x/net v0.19.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/kr/text v0.2.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	golang.org/x/text v0.14.0 // indirect
@@ -0,0 +1,187 @@
+package shelly
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/hashicorp/go-cleanhttp"
+	gen1 "github.com/rubemlrm/go-shelly/shelly/gen1"
+	devices "github.com/rubemlrm/go-shelly/shelly/gen1/devices"
+	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	gen2 "github.com/rubemlrm/go-shelly/shelly/gen2"
+	"github.com/rubemlrm/go-shelly/shelly/models"
+)
+
+// ErrUnsupportedGeneration is returned when the device reports a generation
+// this library has no client for.
+var ErrUnsupportedGeneration = errors.New("unsupported device generation")
+
+// Generation is the API generation of a device.
+type Generation int
+
+const (
+	Gen1 Generation = 1
+	Gen2 Generation = 2
+)
+
+// AuthScheme is the authentication a device expects.
+type AuthScheme int
+
+const (
+	AuthNone AuthScheme = iota
+	// AuthBasic is used by Gen1 devices.
+	AuthBasic
+	// AuthDigest is the SHA-256 digest auth used by Gen2 and newer devices.
+	AuthDigest
+)
+
+// Info is the /shelly response of any generation. Gen2 fields are copied to
+// their Gen1 equivalents, so Type, Mac, Auth and Fw are always set.
+type Info struct {
+	devices.BaseShellyResponse
+	Gen        Generation `json:"gen"`
+	ID         string     `json:"id"`
+	Model      string     `json:"model"`
+	App        string     `json:"app"`
+	Ver        string     `json:"ver"`
+	FwID       string     `json:"fw_id"`
+	AuthEn     bool       `json:"auth_en"`
+	AuthDomain string     `json:"auth_domain"`
+}
+
+// Probe reads /shelly, which never requires authentication, to find out the
+// device generation and if auth is enabled.
+func Probe(ctx context.Context, client *http.Client, address string) (*Info, error) {
+	addr, err := transport.ParseAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	u := addr.URL()
+	u.Path += "/shelly"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("probe of %s returned status %d", addr, resp.StatusCode)
+	}
+
+	var info Info
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return nil, err
+	}
+	// Gen1 devices don't report their generation.
+	if info.Gen == 0 {
+		info.Gen = Gen1
+	}
+	if info.Gen < Gen2 {
+		return &info, nil
+	}
+	info.Type = info.App
+	info.Auth = info.AuthEn
+	info.Fw = info.FwID
+	return &info, nil
+}
+
+// Device is the handle returned by Connect, it wraps the client of the
+// detected generation.
+type Device struct {
+	Info       Info
+	Generation Generation
+	Auth       AuthScheme
+	// Model is nil when the device type isn't in the models registry.
+	Model *models.Model
+	gen1  *gen1.RestClient
+	gen2  *gen2.RestClient
+}
+
+// Gen1 returns the Gen1 client when the device is a Gen1 device.
+func (d *Device) Gen1() (*gen1.RestClient, bool) {
+	return d.gen1, d.gen1 != nil
+}
+
+// Gen2 returns the Gen2 client when the device is a Gen2 or later device.
+func (d *Device) Gen2() (*gen2.RestClient, bool) {
+	return d.gen2, d.gen2 != nil
+}
+
+// BreakerState returns the state of the device circuit, see
+// transport.Client.BreakerState.
+func (d *Device) BreakerState() transport.BreakerState {
+	switch {
+	case d.gen1 != nil:
+		return d.gen1.BreakerState()
+	case d.gen2 != nil:
+		return d.gen2.BreakerState()
+	}
+	return transport.BreakerClosed
+}
+
+// Close stops the background work of the device client.
+func (d *Device) Close() {
+	if d.gen1 != nil {
+		d.gen1.Close()
+	}
+	if d.gen2 != nil {
+		d.gen2.Close()
+	}
+}
+
+// Connect probes the device at address and builds the client that matches
+// its generation and auth settings. The Hostname of opts is replaced by
+// address, credentials are only used when the device has auth enabled.
+func Connect(ctx context.Context, address string, opts transport.ClientOptions) (*Device, error) {
+	info, err := Probe(ctx, cleanhttp.DefaultClient(), address)
+	if err != nil {
+		return nil, err
+	}
+	opts.Hostname = address
+	if opts.MAC == "" {
+		opts.MAC = info.Mac
+	}
+
+	d := &Device{Info: *info, Generation: info.Gen}
+	if m, ok := models.Lookup(info.Type); ok {
+		d.Model = &m
+	}
+	if !info.Auth {
+		opts.Credentials = nil
+	}
+	switch {
+	case info.Gen == Gen1:
+		if info.Auth {
+			d.Auth = AuthBasic
+			d.gen1, err = gen1.NewRestClientWithAuth(opts)
+		} else {
+			d.gen1, err = gen1.NewRestClient(opts)
+		}
+	case info.Gen >= Gen2:
+		// Gen3 and later devices share the Gen2 RPC API.
+		if info.Auth {
+			d.Auth = AuthDigest
+			d.gen2, err = gen2.NewRestClientWithAuth(opts)
+		} else {
+			d.gen2, err = gen2.NewRestClient(opts)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedGeneration, info.Gen)
+	}
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+	if d.gen1 != nil {
+		d.gen1.ShellyService.Model = d.Model
+	}
+	return d, nil
+}
@@ -0,0 +1,216 @@
+package shelly
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func setupDevice(t *testing.T, shelly string) string {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/shelly", func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		fmt.Fprint(w, shelly)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestProbe(t *testing.T) {
+	type test struct {
+		title string
+		body  string
+		want  Info
+	}
+	tests := []test{
+		{
+			title: "Gen1 device",
+			body:  `{"type":"SHSW-25","mac":"A4CF12F45678","auth":true,"fw":"20230913-112003/v1.14.0-gcb84623"}`,
+			want: func() Info {
+				info := Info{Gen: Gen1}
+				info.Type = "SHSW-25"
+				info.Mac = "A4CF12F45678"
+				info.Auth = true
+				info.Fw = "20230913-112003/v1.14.0-gcb84623"
+				return info
+			}(),
+		},
+		{
+			title: "Gen2 device",
+			body:  `{"id":"shellyplus1pm-441793d69718","mac":"441793D69718","model":"SNSW-001P16EU","gen":2,"fw_id":"20231107-164738/1.0.8-g8c7bb8d","ver":"1.0.8","app":"Plus1PM","auth_en":true,"auth_domain":"shellyplus1pm-441793d69718"}`,
+			want: func() Info {
+				info := Info{
+					Gen:        Gen2,
+					ID:         "shellyplus1pm-441793d69718",
+					Model:      "SNSW-001P16EU",
+					App:        "Plus1PM",
+					Ver:        "1.0.8",
+					FwID:       "20231107-164738/1.0.8-g8c7bb8d",
+					AuthEn:     true,
+					AuthDomain: "shellyplus1pm-441793d69718",
+				}
+				info.Type = "Plus1PM"
+				info.Mac = "441793D69718"
+				info.Auth = true
+				info.Fw = "20231107-164738/1.0.8-g8c7bb8d"
+				return info
+			}(),
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			addr := setupDevice(t, tc.body)
+			info, err := Probe(context.Background(), http.DefaultClient, addr)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, *info)
+		})
+	}
+}
+
+func TestProbeErrors(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	_, err := Probe(context.Background(), http.DefaultClient, server.URL)
+	assert.Error(t, err)
+
+	_, err = Probe(context.Background(), http.DefaultClient, "ftp://192.168.1.50")
+	assert.ErrorIs(t, err, transport.ErrUnsupportedScheme)
+}
+
+func TestConnectGen1(t *testing.T) {
+	type test struct {
+		title     string
+		body      string
+		opts      transport.ClientOptions
+		wantAuth  AuthScheme
+		wantError bool
+	}
+	tests := []test{
+		{
+			title:    "Device without auth",
+			body:     `{"type":"SHSW-1","mac":"A4CF12F45678","auth":false}`,
+			wantAuth: AuthNone,
+		},
+		{
+			title:    "Device without auth ignores credentials",
+			body:     `{"type":"SHSW-1","mac":"A4CF12F45678","auth":false}`,
+			opts:     transport.ClientOptions{Credentials: transport.StaticCredentials{Username: "admin", Password: "secret"}},
+			wantAuth: AuthNone,
+		},
+		{
+			title:    "Device with auth",
+			body:     `{"type":"SHSW-1","mac":"A4CF12F45678","auth":true}`,
+			opts:     transport.ClientOptions{Username: "admin", Password: "secret"},
+			wantAuth: AuthBasic,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			addr := setupDevice(t, tc.body)
+			device, err := Connect(context.Background(), addr, tc.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, Gen1, device.Generation)
+			assert.Equal(t, tc.wantAuth, device.Auth)
+
+			client, ok := device.Gen1()
+			assert.True(t, ok)
+			cl := client.ShellyService.Client.(*transport.Client)
+			assert.Equal(t, tc.wantAuth == AuthBasic, cl.RequiresAuth)
+			assert.Equal(t, "Shelly 1", device.Model.Name)
+			assert.Equal(t, device.Model, client.ShellyService.Model)
+		})
+	}
+}
+
+func TestConnectUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	addr := server.URL
+	server.Close()
+
+	_, err := Connect(context.Background(), addr, transport.ClientOptions{})
+	assert.Error(t, err)
+}
+
+func TestDeviceClose(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"SHSW-1","mac":"A4CF12F45678","auth":false}`)
+	}))
+	t.Cleanup(server.Close)
+
+	device, err := Connect(context.Background(), server.URL, transport.ClientOptions{
+		Breaker: &transport.BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Hour, ProbeInterval: 5 * time.Millisecond},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, transport.BreakerClosed, device.BreakerState())
+
+	server.Close()
+	ignore := goleak.IgnoreCurrent()
+	client, _ := device.Gen1()
+	_, _, err = client.ShellyService.GetShelly()
+	assert.Error(t, err)
+	assert.Equal(t, transport.BreakerOpen, device.BreakerState())
+
+	device.Close()
+	goleak.VerifyNone(t, ignore)
+}
+
+func TestConnectGen2(t *testing.T) {
+	type test struct {
+		title    string
+		body     string
+		opts     transport.ClientOptions
+		wantGen  Generation
+		wantAuth AuthScheme
+	}
+	tests := []test{
+		{
+			title:    "Device without auth",
+			body:     `{"id":"shellyplus1pm-441793d69718","mac":"441793D69718","gen":2,"app":"Plus1PM","auth_en":false}`,
+			wantGen:  Gen2,
+			wantAuth: AuthNone,
+		},
+		{
+			title:    "Device with auth",
+			body:     `{"id":"shellyplus1pm-441793d69718","mac":"441793D69718","gen":2,"app":"Plus1PM","auth_en":true,"auth_domain":"shellyplus1pm-441793d69718"}`,
+			opts:     transport.ClientOptions{Password: "secret"},
+			wantGen:  Gen2,
+			wantAuth: AuthDigest,
+		},
+		{
+			title:    "Gen3 device",
+			body:     `{"id":"shelly1g3-84fce63ad204","mac":"84FCE63AD204","gen":3,"app":"S1G3","auth_en":false}`,
+			wantGen:  3,
+			wantAuth: AuthNone,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			addr := setupDevice(t, tc.body)
+			device, err := Connect(context.Background(), addr, tc.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantGen, device.Generation)
+			assert.Equal(t, tc.wantAuth, device.Auth)
+
+			_, ok := device.Gen1()
+			assert.False(t, ok)
+			client, ok := device.Gen2()
+			assert.True(t, ok)
+			assert.NotNil(t, client.RPC)
+		})
+	}
+}
+
+func TestConnectUnsupportedGeneration(t *testing.T) {
+	addr := setupDevice(t, `{"id":"shellyx-84fce63ad204","gen":-1,"app":"Unknown","auth_en":false}`)
+	_, err := Connect(context.Background(), addr, transport.ClientOptions{})
+	assert.ErrorIs(t, err, ErrUnsupportedGeneration)
+}
@@ -0,0 +1,507 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/rubemlrm/go-shelly/shelly"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const mdnsAddress = "224.0.0.251:5353"
+
+// DefaultServices are the DNS-SD services announced by Shelly devices, Gen2
+// devices announce both.
+var DefaultServices = []string{"_http._tcp.local.", "_shelly._tcp.local."}
+
+// EventType tells what happened to a browsed device.
+type EventType int
+
+const (
+	EventAdded EventType = iota + 1
+	EventUpdated
+	EventRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventUpdated:
+		return "updated"
+	case EventRemoved:
+		return "removed"
+	}
+	return "unknown"
+}
+
+// Service is a device announced over mDNS. Info holds the /shelly response
+// and is nil when the device couldn't be reached.
+type Service struct {
+	Instance string
+	Hostname string
+	Addrs    []netip.Addr
+	Port     uint16
+	Services []string
+	TXT      map[string]string
+	Info     *shelly.Info
+}
+
+// Event is sent by Browse when a device is added, updated or removed.
+type Event struct {
+	Type    EventType
+	Service Service
+}
+
+// BrowseOptions configures an mDNS browser.
+type BrowseOptions struct {
+	// Services are the DNS-SD services browsed. Defaults to DefaultServices.
+	Services []string
+	// Conn receives the mDNS responses and sends the queries. Defaults to a
+	// multicast listener on 224.0.0.251:5353.
+	Conn net.PacketConn
+	// Destination is where the queries are sent. Defaults to 224.0.0.251:5353.
+	Destination net.Addr
+	// QueryInterval is the time between queries. Defaults to one minute.
+	QueryInterval time.Duration
+	// Timeout bounds the /shelly call made for every device. Defaults to one
+	// second.
+	Timeout time.Duration
+	// HTTPClient is used to enrich the devices.
+	HTTPClient *http.Client
+	// OnError is called when a query can't be sent or Conn stops reading,
+	// it may be called from different goroutines.
+	OnError func(error)
+}
+
+func (o BrowseOptions) withDefaults() (BrowseOptions, error) {
+	if len(o.Services) == 0 {
+		o.Services = DefaultServices
+	}
+	for i, s := range o.Services {
+		o.Services[i] = dnsName(s)
+	}
+	if o.QueryInterval <= 0 {
+		o.QueryInterval = time.Minute
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = time.Second
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = cleanhttp.DefaultPooledClient()
+	}
+	if o.Destination == nil {
+		dst, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+		if err != nil {
+			return o, err
+		}
+		o.Destination = dst
+	}
+	return o, nil
+}
+
+// Browse listens for Shelly mDNS announcements and sends an event every time
+// a device appears, changes or goes away. Devices are enriched with a /shelly
+// call before they're reported. The channel is closed when ctx is canceled.
+func Browse(ctx context.Context, opts BrowseOptions) (<-chan Event, error) {
+	opts.Services = slices.Clone(opts.Services)
+	opts, err := opts.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	owned := opts.Conn == nil
+	if owned {
+		group, err := net.ResolveUDPAddr("udp4", mdnsAddress)
+		if err != nil {
+			return nil, err
+		}
+		opts.Conn, err = net.ListenMulticastUDP("udp4", nil, group)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	b := &browser{
+		opts:     opts,
+		events:   make(chan Event),
+		packets:  make(chan []byte),
+		probes:   make(chan probeResult),
+		entries:  map[string]*entry{},
+		hosts:    map[string]host{},
+		ctx:      ctx,
+		ownsConn: owned,
+		stopped:  make(chan struct{}),
+	}
+	go b.read()
+	go b.run()
+	return b.events, nil
+}
+
+type entry struct {
+	service   Service
+	expires   time.Time
+	announced bool
+	version   int
+}
+
+// host holds the addresses of a hostname until its records expire.
+type host struct {
+	addrs   []netip.Addr
+	expires time.Time
+}
+
+type probeResult struct {
+	key     string
+	version int
+	info    *shelly.Info
+}
+
+type browser struct {
+	opts     BrowseOptions
+	events   chan Event
+	packets  chan []byte
+	probes   chan probeResult
+	entries  map[string]*entry
+	hosts    map[string]host
+	ctx      context.Context
+	ownsConn bool
+	// stopped is closed when the reader returns.
+	stopped chan struct{}
+}
+
+func (b *browser) read() {
+	defer close(b.stopped)
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := b.opts.Conn.ReadFrom(buf)
+		if err != nil {
+			if b.ctx.Err() != nil {
+				return
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			b.error(fmt.Errorf("mdns read: %w", err))
+			return
+		}
+		select {
+		case b.packets <- slices.Clone(buf[:n]):
+		case <-b.ctx.Done():
+			return
+		}
+	}
+}
+
+func (b *browser) run() {
+	defer close(b.events)
+	defer func() {
+		if b.ownsConn {
+			b.opts.Conn.Close()
+			return
+		}
+		// Unblock the reader without closing a connection we don't own,
+		// then clear the deadline so the caller can keep using it.
+		b.opts.Conn.SetReadDeadline(time.Now())
+		<-b.stopped
+		b.opts.Conn.SetReadDeadline(time.Time{})
+	}()
+
+	queries := time.NewTicker(b.opts.QueryInterval)
+	defer queries.Stop()
+	expiry := time.NewTicker(time.Second)
+	defer expiry.Stop()
+
+	b.query()
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+		case <-queries.C:
+			b.query()
+		case now := <-expiry.C:
+			b.expire(now)
+		case packet := <-b.packets:
+			b.handle(packet)
+		case result := <-b.probes:
+			b.enriched(result)
+		}
+	}
+}
+
+func (b *browser) query() {
+	msg := dnsmessage.Message{}
+	for _, s := range b.opts.Services {
+		name, err := dnsmessage.NewName(s)
+		if err != nil {
+			continue
+		}
+		msg.Questions = append(msg.Questions, dnsmessage.Question{
+			Name:  name,
+			Type:  dnsmessage.TypePTR,
+			Class: dnsmessage.ClassINET,
+		})
+	}
+	packet, err := msg.Pack()
+	if err != nil {
+		return
+	}
+	if _, err := b.opts.Conn.WriteTo(packet, b.opts.Destination); err != nil {
+		b.error(fmt.Errorf("mdns query: %w", err))
+	}
+}
+
+func (b *browser) error(err error) {
+	if b.opts.OnError != nil {
+		b.opts.OnError(err)
+	}
+}
+
+func (b *browser) handle(packet []byte) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(packet); err != nil || !msg.Header.Response {
+		return
+	}
+	records := append(append(msg.Answers, msg.Authorities...), msg.Additionals...)
+
+	// Addresses come first so the services in the same packet can use them.
+	now := time.Now()
+	hosts := map[string]host{}
+	for _, r := range records {
+		if r.Header.TTL == 0 {
+			continue
+		}
+		name := dnsName(r.Header.Name.String())
+		var addr netip.Addr
+		switch body := r.Body.(type) {
+		case *dnsmessage.AResource:
+			addr = netip.AddrFrom4(body.A)
+		case *dnsmessage.AAAAResource:
+			addr = netip.AddrFrom16(body.AAAA)
+		default:
+			continue
+		}
+		h := hosts[name]
+		h.addrs = append(h.addrs, addr)
+		expires := now.Add(time.Duration(r.Header.TTL) * time.Second)
+		if h.expires.IsZero() || expires.Before(h.expires) {
+			h.expires = expires
+		}
+		hosts[name] = h
+	}
+	maps.Copy(b.hosts, hosts)
+
+	touched := map[string]bool{}
+	for _, r := range records {
+		name := dnsName(r.Header.Name.String())
+		switch body := r.Body.(type) {
+		case *dnsmessage.PTRResource:
+			if !slices.Contains(b.opts.Services, name) {
+				continue
+			}
+			instance, ok := b.instance(fqdn(body.PTR.String()), name)
+			if !ok {
+				continue
+			}
+			key := strings.ToLower(instance)
+			if r.Header.TTL == 0 {
+				b.remove(key)
+				delete(touched, key)
+				continue
+			}
+			e := b.entry(key, instance)
+			if !slices.Contains(e.service.Services, name) {
+				e.service.Services = append(e.service.Services, name)
+				touched[key] = true
+			}
+			e.expires = maxTime(e.expires, now.Add(time.Duration(r.Header.TTL)*time.Second))
+		case *dnsmessage.SRVResource:
+			key, e := b.lookup(name)
+			if e == nil || r.Header.TTL == 0 {
+				continue
+			}
+			host := dnsName(body.Target.String())
+			if e.service.Hostname != host || e.service.Port != body.Port {
+				e.service.Hostname = host
+				e.service.Port = body.Port
+				touched[key] = true
+			}
+		case *dnsmessage.TXTResource:
+			key, e := b.lookup(name)
+			if e == nil || r.Header.TTL == 0 {
+				continue
+			}
+			txt := parseTXT(body.TXT)
+			if !maps.Equal(e.service.TXT, txt) {
+				e.service.TXT = txt
+				touched[key] = true
+			}
+		}
+	}
+
+	for key, e := range b.entries {
+		if h, ok := b.hosts[e.service.Hostname]; ok && !slices.Equal(h.addrs, e.service.Addrs) {
+			e.service.Addrs = slices.Clone(h.addrs)
+			touched[key] = true
+		}
+	}
+	for key := range touched {
+		e, ok := b.entries[key]
+		if !ok {
+			continue
+		}
+		if e.service.Port == 0 || len(e.service.Addrs) == 0 {
+			continue
+		}
+		e.version++
+		go b.probe(key, e.version, e.service)
+	}
+}
+
+// instance returns the instance label of a service name, devices other than
+// Shelly's announcing _http._tcp are skipped.
+func (b *browser) instance(name, service string) (string, bool) {
+	suffix := "." + service
+	if !strings.HasSuffix(strings.ToLower(name), suffix) {
+		return "", false
+	}
+	instance := name[:len(name)-len(suffix)]
+	if instance == "" {
+		return "", false
+	}
+	if service != "_shelly._tcp.local." && !strings.HasPrefix(strings.ToLower(instance), "shelly") {
+		return "", false
+	}
+	return instance, true
+}
+
+func (b *browser) entry(key, instance string) *entry {
+	e, ok := b.entries[key]
+	if !ok {
+		e = &entry{service: Service{Instance: instance}}
+		b.entries[key] = e
+	}
+	return e
+}
+
+// lookup finds the entry of a full service instance name, it must have been
+// announced by a PTR record first.
+func (b *browser) lookup(name string) (string, *entry) {
+	for _, s := range b.opts.Services {
+		instance, ok := b.instance(name, s)
+		if !ok {
+			continue
+		}
+		key := strings.ToLower(instance)
+		if e, ok := b.entries[key]; ok {
+			return key, e
+		}
+	}
+	return "", nil
+}
+
+func (b *browser) probe(key string, version int, service Service) {
+	ctx, cancel := context.WithTimeout(b.ctx, b.opts.Timeout)
+	defer cancel()
+	addr := service.Addrs[0]
+	for _, a := range service.Addrs {
+		if a.Is4() {
+			addr = a
+			break
+		}
+	}
+	info, err := shelly.Probe(ctx, b.opts.HTTPClient, netip.AddrPortFrom(addr, service.Port).String())
+	if err != nil {
+		info = nil
+	}
+	select {
+	case b.probes <- probeResult{key: key, version: version, info: info}:
+	case <-b.ctx.Done():
+	}
+}
+
+func (b *browser) enriched(result probeResult) {
+	e, ok := b.entries[result.key]
+	if !ok || e.version != result.version {
+		return
+	}
+	e.service.Info = result.info
+	event := EventUpdated
+	if !e.announced {
+		event = EventAdded
+		e.announced = true
+	}
+	b.emit(event, e.service)
+}
+
+func (b *browser) remove(key string) {
+	e, ok := b.entries[key]
+	if !ok {
+		return
+	}
+	delete(b.entries, key)
+	if e.announced {
+		b.emit(EventRemoved, e.service)
+	}
+}
+
+func (b *browser) expire(now time.Time) {
+	for key, e := range b.entries {
+		if now.After(e.expires) {
+			b.remove(key)
+		}
+	}
+	for name, h := range b.hosts {
+		if now.After(h.expires) {
+			delete(b.hosts, name)
+		}
+	}
+}
+
+func (b *browser) emit(t EventType, service Service) {
+	service.Addrs = slices.Clone(service.Addrs)
+	service.Services = slices.Clone(service.Services)
+	select {
+	case b.events <- Event{Type: t, Service: service}:
+	case <-b.ctx.Done():
+	}
+}
+
+func fqdn(name string) string {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// dnsName lower cases a name and makes it fully qualified.
+func dnsName(name string) string {
+	return strings.ToLower(fqdn(name))
+}
+
+func parseTXT(records []string) map[string]string {
+	txt := make(map[string]string, len(records))
+	for _, r := range records {
+		k, v, _ := strings.Cut(r, "=")
+		if k != "" {
+			txt[strings.ToLower(k)] = v
+		}
+	}
+	return txt
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
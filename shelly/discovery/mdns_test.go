@@ -0,0 +1,213 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+type responder struct {
+	t    *testing.T
+	conn net.PacketConn
+	port uint16
+}
+
+// setupResponder answers the browser queries over loopback the way a Gen2
+// device and an unrelated printer would.
+func setupResponder(t *testing.T, port uint16) *responder {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	r := &responder{t: t, conn: conn, port: port}
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			var msg dnsmessage.Message
+			if msg.Unpack(buf[:n]) != nil || msg.Header.Response {
+				continue
+			}
+			r.announce(addr, 120, "1.0.8")
+		}
+	}()
+	return r
+}
+
+func (r *responder) announce(to net.Addr, ttl uint32, version string) {
+	service := dnsmessage.MustNewName("_http._tcp.local.")
+	instance := dnsmessage.MustNewName("ShellyPlus1PM-441793D69718._http._tcp.local.")
+	printer := dnsmessage.MustNewName("printer._http._tcp.local.")
+	host := dnsmessage.MustNewName("shellyplus1pm-441793d69718.local.")
+	header := func(name dnsmessage.Name, t dnsmessage.Type) dnsmessage.ResourceHeader {
+		return dnsmessage.ResourceHeader{Name: name, Type: t, Class: dnsmessage.ClassINET, TTL: ttl}
+	}
+	msg := dnsmessage.Message{
+		Header: dnsmessage.Header{Response: true, Authoritative: true},
+		Answers: []dnsmessage.Resource{
+			{Header: header(service, dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: printer}},
+			{Header: header(service, dnsmessage.TypePTR), Body: &dnsmessage.PTRResource{PTR: instance}},
+		},
+		Additionals: []dnsmessage.Resource{
+			{Header: header(instance, dnsmessage.TypeSRV), Body: &dnsmessage.SRVResource{Target: host, Port: r.port}},
+			{Header: header(instance, dnsmessage.TypeTXT), Body: &dnsmessage.TXTResource{TXT: []string{"gen=2", "app=Plus1PM", "ver=" + version}}},
+			{Header: header(host, dnsmessage.TypeA), Body: &dnsmessage.AResource{A: [4]byte{127, 0, 0, 1}}},
+		},
+	}
+	packet, err := msg.Pack()
+	assert.NoError(r.t, err)
+	_, err = r.conn.WriteTo(packet, to)
+	assert.NoError(r.t, err)
+}
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	select {
+	case event, ok := <-events:
+		assert.True(t, ok)
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+	}
+	return Event{}
+}
+
+func TestBrowse(t *testing.T) {
+	port := setupDevice(t, `{"id":"shellyplus1pm-441793d69718","mac":"441793D69718","gen":2,"app":"Plus1PM","fw_id":"20231107-164738/1.0.8-g8c7bb8d","auth_en":false}`)
+	r := setupResponder(t, port)
+
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Browse(ctx, BrowseOptions{Conn: conn, Destination: r.conn.LocalAddr()})
+	assert.NoError(t, err)
+
+	event := nextEvent(t, events)
+	assert.Equal(t, EventAdded, event.Type)
+	assert.Equal(t, "ShellyPlus1PM-441793D69718", event.Service.Instance)
+	assert.Equal(t, "shellyplus1pm-441793d69718.local.", event.Service.Hostname)
+	assert.Equal(t, port, event.Service.Port)
+	assert.Equal(t, "127.0.0.1", event.Service.Addrs[0].String())
+	assert.Equal(t, "1.0.8", event.Service.TXT["ver"])
+	if assert.NotNil(t, event.Service.Info) {
+		assert.Equal(t, "441793D69718", event.Service.Info.Mac)
+		assert.Equal(t, "Plus1PM", event.Service.Info.Type)
+	}
+
+	r.announce(conn.LocalAddr(), 120, "1.1.0")
+	event = nextEvent(t, events)
+	assert.Equal(t, EventUpdated, event.Type)
+	assert.Equal(t, "1.1.0", event.Service.TXT["ver"])
+
+	r.announce(conn.LocalAddr(), 0, "1.1.0")
+	event = nextEvent(t, events)
+	assert.Equal(t, EventRemoved, event.Type)
+	assert.Equal(t, "ShellyPlus1PM-441793D69718", event.Service.Instance)
+
+	cancel()
+	for range events {
+	}
+}
+
+func TestBrowseInstance(t *testing.T) {
+	type test struct {
+		title   string
+		name    string
+		service string
+		want    string
+		ok      bool
+	}
+	tests := []test{
+		{title: "Gen1 http service", name: "shelly1pm-A4CF12F45678._http._tcp.local.", service: "_http._tcp.local.", want: "shelly1pm-A4CF12F45678", ok: true},
+		{title: "Gen2 shelly service", name: "ShellyPlus1-441793D69718._shelly._tcp.local.", service: "_shelly._tcp.local.", want: "ShellyPlus1-441793D69718", ok: true},
+		{title: "Other http device", name: "printer._http._tcp.local.", service: "_http._tcp.local."},
+		{title: "Other service", name: "shelly1._ipp._tcp.local.", service: "_http._tcp.local."},
+	}
+	b := &browser{}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			got, ok := b.instance(tc.name, tc.service)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestBrowseKeepsSuppliedConn(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	peer, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { peer.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := Browse(ctx, BrowseOptions{Conn: conn, Destination: peer.LocalAddr()})
+	assert.NoError(t, err)
+	cancel()
+	for range events {
+	}
+
+	_, err = peer.WriteTo([]byte("ping"), conn.LocalAddr())
+	assert.NoError(t, err)
+	buf := make([]byte, 16)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+}
+
+type failingConn struct {
+	net.PacketConn
+}
+
+func (c failingConn) WriteTo(p []byte, addr net.Addr) (int, error) {
+	return 0, errors.New("network is unreachable")
+}
+
+func TestBrowseQueryError(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	errs := make(chan error, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := Browse(ctx, BrowseOptions{
+		Conn:        failingConn{conn},
+		Destination: conn.LocalAddr(),
+		OnError:     func(err error) { errs <- err },
+	})
+	assert.NoError(t, err)
+	select {
+	case err := <-errs:
+		assert.ErrorContains(t, err, "network is unreachable")
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the query error")
+	}
+	cancel()
+	for range events {
+	}
+}
+
+func TestBrowseExpiresHosts(t *testing.T) {
+	now := time.Now()
+	b := &browser{
+		entries: map[string]*entry{},
+		hosts: map[string]host{
+			"expired.local.": {addrs: []netip.Addr{netip.MustParseAddr("192.168.1.10")}, expires: now.Add(-time.Second)},
+			"alive.local.":   {addrs: []netip.Addr{netip.MustParseAddr("192.168.1.11")}, expires: now.Add(time.Minute)},
+		},
+	}
+	b.expire(now)
+	assert.Len(t, b.hosts, 1)
+	assert.Contains(t, b.hosts, "alive.local.")
+}
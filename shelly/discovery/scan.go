@@ -0,0 +1,196 @@
+package discovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+	"github.com/rubemlrm/go-shelly/shelly"
+)
+
+// ErrRangeTooLarge is returned when the ranges to scan hold more addresses
+// than ScanOptions.MaxHosts.
+var ErrRangeTooLarge = errors.New("scan range too large")
+
+// Record is a device found by a scan.
+type Record struct {
+	IP netip.Addr
+	shelly.Info
+}
+
+// Result is streamed for every probed address. Record is nil when no
+// Shelly device answered on the address.
+type Result struct {
+	Addr    netip.Addr
+	Record  *Record
+	Scanned int
+	Total   int
+}
+
+// ScanOptions configures a subnet scan.
+type ScanOptions struct {
+	// Workers is the number of concurrent probes. Defaults to 64.
+	Workers int
+	// Timeout bounds each probe. Defaults to one second.
+	Timeout time.Duration
+	// Port is the HTTP port probed on every address. Defaults to 80.
+	Port uint16
+	// MaxHosts bounds the number of addresses scanned. Defaults to 65536.
+	MaxHosts int
+	// HTTPClient is used for the probes.
+	HTTPClient *http.Client
+}
+
+func (o ScanOptions) withDefaults() ScanOptions {
+	if o.Workers <= 0 {
+		o.Workers = 64
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = time.Second
+	}
+	if o.Port == 0 {
+		o.Port = 80
+	}
+	if o.MaxHosts <= 0 {
+		o.MaxHosts = 1 << 16
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = cleanhttp.DefaultPooledClient()
+	}
+	return o
+}
+
+// Scan probes /shelly on every host of the given CIDR ranges and returns the
+// devices found, sorted by IP. Single addresses are accepted as well.
+func Scan(ctx context.Context, ranges []string, opts ScanOptions) ([]Record, error) {
+	results, err := ScanStream(ctx, ranges, opts)
+	if err != nil {
+		return nil, err
+	}
+	var records []Record
+	for result := range results {
+		if result.Record != nil {
+			records = append(records, *result.Record)
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return records, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].IP.Less(records[j].IP)
+	})
+	return records, nil
+}
+
+// ScanStream works like Scan but sends a Result for every probed address,
+// so callers can show progress. The channel is closed when the scan ends or
+// ctx is canceled.
+func ScanStream(ctx context.Context, ranges []string, opts ScanOptions) (<-chan Result, error) {
+	opts = opts.withDefaults()
+	hosts, err := expandRanges(ranges, opts.MaxHosts)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make(chan netip.Addr)
+	results := make(chan Result)
+	var mu sync.Mutex
+	scanned := 0
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(opts.Workers, len(hosts)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for addr := range jobs {
+				record := probe(ctx, opts, addr)
+				mu.Lock()
+				scanned++
+				result := Result{Addr: addr, Record: record, Scanned: scanned, Total: len(hosts)}
+				mu.Unlock()
+				select {
+				case results <- result:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		for _, addr := range hosts {
+			select {
+			case jobs <- addr:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results, nil
+}
+
+func probe(ctx context.Context, opts ScanOptions, addr netip.Addr) *Record {
+	ctx, cancel := context.WithTimeout(ctx, opts.Timeout)
+	defer cancel()
+	info, err := shelly.Probe(ctx, opts.HTTPClient, netip.AddrPortFrom(addr, opts.Port).String())
+	if err != nil || (info.Mac == "" && info.Type == "") {
+		return nil
+	}
+	return &Record{IP: addr, Info: *info}
+}
+
+// expandRanges lists the hosts of the ranges, leaving out the network and
+// broadcast addresses of IPv4 subnets.
+func expandRanges(ranges []string, maxHosts int) ([]netip.Addr, error) {
+	var hosts []netip.Addr
+	seen := map[netip.Addr]bool{}
+	for _, r := range ranges {
+		r = strings.TrimSpace(r)
+		if !strings.Contains(r, "/") {
+			addr, err := netip.ParseAddr(r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid scan range %q: %w", r, err)
+			}
+			r = netip.PrefixFrom(addr, addr.BitLen()).String()
+		}
+		prefix, err := netip.ParsePrefix(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid scan range %q: %w", r, err)
+		}
+		prefix = prefix.Masked()
+		hostBits := prefix.Addr().BitLen() - prefix.Bits()
+		if hostBits > 30 || 1<<hostBits > maxHosts+2 {
+			return nil, fmt.Errorf("%w: %s", ErrRangeTooLarge, r)
+		}
+
+		skipEdges := prefix.Addr().Is4() && hostBits > 1
+		first := prefix.Addr()
+		for addr := first; prefix.Contains(addr); addr = addr.Next() {
+			if skipEdges && (addr == first || !prefix.Contains(addr.Next())) {
+				continue
+			}
+			if !seen[addr] {
+				seen[addr] = true
+				hosts = append(hosts, addr)
+			}
+			if !addr.Next().IsValid() {
+				break
+			}
+		}
+		if len(hosts) > maxHosts {
+			return nil, fmt.Errorf("%w: %s", ErrRangeTooLarge, r)
+		}
+	}
+	return hosts, nil
+}
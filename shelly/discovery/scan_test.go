@@ -0,0 +1,165 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/rubemlrm/go-shelly/shelly"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDevice(t *testing.T, body string) uint16 {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/shelly", r.URL.Path)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(server.Close)
+	addr, err := netip.ParseAddrPort(server.Listener.Addr().String())
+	assert.NoError(t, err)
+	return addr.Port()
+}
+
+func TestScan(t *testing.T) {
+	type test struct {
+		title   string
+		body    string
+		wantGen shelly.Generation
+		want    string
+	}
+	tests := []test{
+		{
+			title:   "Gen1 device",
+			body:    `{"type":"SHSW-25","mac":"A4CF12F45678","auth":true,"fw":"20230913-112003/v1.14.0-gcb84623"}`,
+			wantGen: shelly.Gen1,
+			want:    "SHSW-25",
+		},
+		{
+			title:   "Gen2 device",
+			body:    `{"id":"shellyplus1-441793d69718","mac":"441793D69718","gen":2,"app":"Plus1","fw_id":"20231107-164738/1.0.8-g8c7bb8d","auth_en":false}`,
+			wantGen: shelly.Gen2,
+			want:    "Plus1",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			port := setupDevice(t, tc.body)
+			records, err := Scan(context.Background(), []string{"127.0.0.0/30"}, ScanOptions{
+				Port:    port,
+				Timeout: time.Second,
+			})
+			assert.NoError(t, err)
+			assert.Len(t, records, 1)
+			assert.Equal(t, netip.MustParseAddr("127.0.0.1"), records[0].IP)
+			assert.Equal(t, tc.wantGen, records[0].Gen)
+			assert.Equal(t, tc.want, records[0].Type)
+			assert.NotEmpty(t, records[0].Mac)
+			assert.NotEmpty(t, records[0].Fw)
+		})
+	}
+}
+
+func TestScanIgnoresOtherServers(t *testing.T) {
+	port := setupDevice(t, `{"hello":"world"}`)
+	records, err := Scan(context.Background(), []string{"127.0.0.1"}, ScanOptions{Port: port})
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestScanStream(t *testing.T) {
+	port := setupDevice(t, `{"type":"SHSW-1","mac":"A4CF12F45678"}`)
+	results, err := ScanStream(context.Background(), []string{"127.0.0.0/29"}, ScanOptions{Port: port, Workers: 2})
+	assert.NoError(t, err)
+
+	var found []Record
+	scanned := 0
+	for result := range results {
+		assert.Equal(t, 6, result.Total)
+		assert.Greater(t, result.Scanned, scanned)
+		scanned = result.Scanned
+		if result.Record != nil {
+			found = append(found, *result.Record)
+		}
+	}
+	assert.Equal(t, 6, scanned)
+	assert.Len(t, found, 1)
+}
+
+func TestScanCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Scan(ctx, []string{"127.0.0.0/29"}, ScanOptions{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestExpandRanges(t *testing.T) {
+	type test struct {
+		title     string
+		ranges    []string
+		maxHosts  int
+		want      []string
+		wantError error
+	}
+	tests := []test{
+		{
+			title:  "Subnet without network and broadcast",
+			ranges: []string{"192.168.1.0/30"},
+			want:   []string{"192.168.1.1", "192.168.1.2"},
+		},
+		{
+			title:  "Unmasked prefix and duplicated address",
+			ranges: []string{"192.168.1.5/30", "192.168.1.6"},
+			want:   []string{"192.168.1.5", "192.168.1.6"},
+		},
+		{
+			title:  "Point to point subnet",
+			ranges: []string{"10.0.0.0/31"},
+			want:   []string{"10.0.0.0", "10.0.0.1"},
+		},
+		{
+			title:  "Ipv6 subnet",
+			ranges: []string{"fd00::/127"},
+			want:   []string{"fd00::", "fd00::1"},
+		},
+		{
+			title:     "Too many hosts",
+			ranges:    []string{"10.0.0.0/24"},
+			maxHosts:  100,
+			wantError: ErrRangeTooLarge,
+		},
+		{
+			title:     "Ipv6 subnet too large",
+			ranges:    []string{"fd00::/64"},
+			wantError: ErrRangeTooLarge,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			if tc.maxHosts == 0 {
+				tc.maxHosts = 1 << 16
+			}
+			hosts, err := expandRanges(tc.ranges, tc.maxHosts)
+			if tc.wantError != nil {
+				assert.ErrorIs(t, err, tc.wantError)
+				return
+			}
+			assert.NoError(t, err)
+			var got []string
+			for _, h := range hosts {
+				got = append(got, h.String())
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExpandRangesInvalid(t *testing.T) {
+	_, err := expandRanges([]string{"192.168.1.0/33"}, 10)
+	assert.Error(t, err)
+	_, err = expandRanges([]string{"shelly.local"}, 10)
+	assert.Error(t, err)
+}
@@ -0,0 +1,34 @@
+package gen1
+
+import (
+	"context"
+	"encoding/json"
+
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+)
+
+// Call sends a request to any Gen1 endpoint and decodes the response into T.
+// It goes through the client NewRequestWithContext and Do, so auth and
+// retries apply to endpoints that still don't have a typed service method.
+func Call[T any](ctx context.Context, client contracts.ShellyClient, method, endpoint string, params interface{}) (*T, *contracts.Response, error) {
+	req, err := client.NewRequestWithContext(ctx, method, endpoint, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out T
+	resp, err := client.Do(req, &out)
+	if err != nil {
+		return nil, resp, err
+	}
+	return &out, resp, nil
+}
+
+// CallRaw works like Call but returns the undecoded JSON response.
+func CallRaw(ctx context.Context, client contracts.ShellyClient, method, endpoint string, params interface{}) (json.RawMessage, *contracts.Response, error) {
+	raw, resp, err := Call[json.RawMessage](ctx, client, method, endpoint, params)
+	if err != nil {
+		return nil, resp, err
+	}
+	return *raw, resp, nil
+}
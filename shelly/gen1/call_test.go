@@ -0,0 +1,159 @@
+package gen1
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen1/contracts/mocks"
+	"github.com/rubemlrm/go-shelly/shelly/gen1/devices"
+	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupCallClient(t *testing.T) (*http.ServeMux, *transport.Client) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := transport.NewRestBasicAuthClient(transport.ClientOptions{
+		Hostname: server.URL,
+		Username: "admin",
+		Password: "secret",
+	})
+	assert.NoError(t, err)
+	return mux, client
+}
+
+func TestCall(t *testing.T) {
+	type params struct {
+		Turn string `url:"turn"`
+	}
+	type relay struct {
+		IsOn   bool   `json:"ison"`
+		Source string `json:"source"`
+	}
+
+	type test struct {
+		title     string
+		body      string
+		status    int
+		want      *relay
+		wantError bool
+	}
+	tests := []test{
+		{
+			title:  "Typed response decoded with success",
+			body:   `{"ison":true,"source":"http"}`,
+			status: http.StatusOK,
+			want:   &relay{IsOn: true, Source: "http"},
+		},
+		{
+			title:     "Decode error is returned",
+			body:      `{"ison":"maybe"}`,
+			status:    http.StatusOK,
+			wantError: true,
+		},
+		{
+			title:     "Unauthorized error is returned",
+			status:    http.StatusUnauthorized,
+			wantError: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			mux, client := setupCallClient(t)
+			mux.HandleFunc("/relay/0", func(w http.ResponseWriter, r *http.Request) {
+				user, pass, ok := r.BasicAuth()
+				assert.True(t, ok)
+				assert.Equal(t, "admin", user)
+				assert.Equal(t, "secret", pass)
+				assert.Equal(t, "on", r.URL.Query().Get("turn"))
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			})
+
+			got, resp, err := Call[relay](context.Background(), client, http.MethodGet, "/relay/0", &params{Turn: "on"})
+			if tc.wantError {
+				assert.Error(t, err)
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+				assert.Equal(t, http.StatusOK, resp.StatusCode)
+			}
+		})
+	}
+}
+
+func TestCallWithServiceTypes(t *testing.T) {
+	mux, client := setupCallClient(t)
+	mux.HandleFunc("/shelly", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"type":"SHSW-1","mac":"A4CF12F45678","auth":true}`)
+	})
+
+	got, _, err := Call[devices.BaseShellyResponse](context.Background(), client, http.MethodGet, "/shelly", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "SHSW-1", got.Type)
+}
+
+func TestCallRaw(t *testing.T) {
+	mux, client := setupCallClient(t)
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"uptime":42}`)
+	})
+
+	raw, _, err := CallRaw(context.Background(), client, http.MethodGet, "/status", nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"uptime":42}`, string(raw))
+}
+
+func TestCallCanceledContext(t *testing.T) {
+	_, client := setupCallClient(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := CallRaw(ctx, client, http.MethodGet, "/status", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+type countingProvider struct {
+	calls int32
+}
+
+func (p *countingProvider) Credentials(ctx context.Context, _ transport.DeviceIdentity) (transport.Credentials, error) {
+	atomic.AddInt32(&p.calls, 1)
+	return transport.Credentials{Username: "admin", Password: "secret"}, nil
+}
+
+func TestCallCanceledContextSkipsCredentials(t *testing.T) {
+	var hits int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	provider := &countingProvider{}
+	client, err := transport.NewRestBasicAuthClient(transport.ClientOptions{Hostname: server.URL, Credentials: provider})
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err = CallRaw(ctx, client, http.MethodGet, "/status", nil)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Zero(t, atomic.LoadInt32(&provider.calls))
+	assert.Zero(t, atomic.LoadInt32(&hits))
+}
+
+func TestCallNewRequestFailure(t *testing.T) {
+	client := mocks.NewShellyClient(t)
+	client.On("NewRequestWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
+	_, _, err := CallRaw(context.Background(), client, http.MethodGet, "/status", nil)
+	assert.Error(t, err)
+}
@@ -7,6 +7,7 @@ import (
 
 type RestClient struct {
 	ShellyService *devices.ShellyService
+	client        *transport.Client
 }
 
 func NewRestClient(options transport.ClientOptions) (*RestClient, error) {
@@ -16,6 +17,7 @@ func NewRestClient(options transport.ClientOptions) (*RestClient, error) {
 	}
 	return &RestClient{
 		ShellyService: devices.NewShellyService(cl),
+		client:        cl,
 	}, nil
 }
 
@@ -26,5 +28,18 @@ func NewRestClientWithAuth(options transport.ClientOptions) (*RestClient, error)
 	}
 	return &RestClient{
 		ShellyService: devices.NewShellyService(cl),
+		client:        cl,
 	}, nil
 }
+
+// BreakerState returns the state of the device circuit, see
+// transport.Client.BreakerState.
+func (c *RestClient) BreakerState() transport.BreakerState {
+	return c.client.BreakerState()
+}
+
+// Close stops the background work of the client, like the circuit breaker
+// probe.
+func (c *RestClient) Close() {
+	c.client.Close()
+}
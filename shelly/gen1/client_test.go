@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/go-faker/faker/v4"
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
 	"github.com/rubemlrm/go-shelly/shelly/gen1/devices"
 	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
 	"github.com/stretchr/testify/assert"
@@ -57,7 +58,8 @@ func TestNewRestClient(t *testing.T) {
 				assert.NoError(t, err)
 				v := client.ShellyService.Client.(*transport.Client)
 				assert.Equal(t, v.BaseURL, tt.wants.ShellyService.Client.(*transport.Client).BaseURL)
-
+				assert.Equal(t, transport.BreakerClosed, client.BreakerState())
+				client.Close()
 			}
 		})
 	}
@@ -68,7 +70,7 @@ func TestNewRestClientWithAuth(t *testing.T) {
 		options transport.ClientOptions
 	}
 	username := faker.Username()
-	password := faker.Password()
+	password := contracts.Secret(faker.Password())
 	url, err := url.Parse("http://localhost")
 	assert.NoError(t, err)
 
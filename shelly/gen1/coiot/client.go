@@ -0,0 +1,304 @@
+package coiot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-querystring/query"
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+)
+
+// DefaultPort is the CoAP port Gen1 devices answer on.
+const DefaultPort = 5683
+
+var (
+	// ErrTimeout is returned when the device didn't answer in time.
+	ErrTimeout = errors.New("coap request timed out")
+	// ErrReset is returned when the device rejected the request.
+	ErrReset = errors.New("coap request reset by the device")
+	// ErrUnsupportedMethod is returned for methods other than GET.
+	ErrUnsupportedMethod = errors.New("coap client only supports GET")
+)
+
+// StatusError is returned when the device answers with a code outside of the
+// 2.xx class. StatusCode is the closest HTTP status, zero when there's none.
+type StatusError struct {
+	Code       uint8
+	StatusCode int
+	Payload    []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("coap request failed with code %s", codeString(e.Code))
+}
+
+var _ contracts.ShellyClient = (*Client)(nil)
+
+// ClientOptions configures a CoAP client.
+type ClientOptions struct {
+	// Hostname is the device address, the port defaults to 5683.
+	Hostname string
+	// AckTimeout is the wait before the first retransmission, it doubles on
+	// every retransmission. Defaults to two seconds.
+	AckTimeout time.Duration
+	// MaxRetransmit is the number of retransmissions of a request. Defaults
+	// to four.
+	MaxRetransmit int
+	// Timeout bounds a whole request, retransmissions included. Defaults to
+	// 30 seconds.
+	Timeout time.Duration
+}
+
+// Client sends GET requests to a Gen1 device over CoAP. It implements
+// contracts.ShellyClient so services can read /cit/d and /cit/s without HTTP.
+type Client struct {
+	BaseURL       *url.URL
+	ackTimeout    time.Duration
+	maxRetransmit int
+	timeout       time.Duration
+	messageID     uint32
+}
+
+// NewClient creates a CoAP client.
+func NewClient(opts ClientOptions) (*Client, error) {
+	host := strings.TrimPrefix(opts.Hostname, "coap://")
+	host = strings.TrimSuffix(host, "/")
+	if host == "" {
+		return nil, fmt.Errorf("hostname can't be empty")
+	}
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(DefaultPort))
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = 2 * time.Second
+	}
+	if opts.MaxRetransmit <= 0 {
+		opts.MaxRetransmit = 4
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 30 * time.Second
+	}
+	var seed [2]byte
+	rand.Read(seed[:])
+	return &Client{
+		BaseURL:       &url.URL{Scheme: "coap", Host: host},
+		ackTimeout:    opts.AckTimeout,
+		maxRetransmit: opts.MaxRetransmit,
+		timeout:       opts.Timeout,
+		messageID:     uint32(binary.BigEndian.Uint16(seed[:])),
+	}, nil
+}
+
+func (c *Client) RetryHTTPCheck(ctx context.Context, resp *http.Response, err error) (bool, error) {
+	if ctx.Err() != nil {
+		return false, ctx.Err()
+	}
+	return false, err
+}
+
+func (c *Client) NewRequest(method, endpoint string, opts interface{}) (*retryablehttp.Request, error) {
+	return c.NewRequestWithContext(context.Background(), method, endpoint, opts)
+}
+
+func (c *Client) NewRequestWithContext(ctx context.Context, method, endpoint string, opts interface{}) (*retryablehttp.Request, error) {
+	if method != http.MethodGet {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMethod, method)
+	}
+	u, err := c.ParseUrl(method, endpoint, opts)
+	if err != nil {
+		return nil, err
+	}
+	return retryablehttp.NewRequestWithContext(ctx, method, u, nil)
+}
+
+func (c *Client) ParseUrl(method, endpoint string, opts interface{}) (string, error) {
+	u := *c.BaseURL
+	u.Path = endpoint
+	if opts != nil {
+		q, err := query.Values(opts)
+		if err != nil {
+			return "", err
+		}
+		u.RawQuery = q.Encode()
+	}
+	return u.String(), nil
+}
+
+func (c *Client) SetAdditionalHeaders(request *retryablehttp.Request, headers http.Header) {
+	for k, v := range headers {
+		request.Header[k] = v
+	}
+}
+
+// SetBasicAuth is a no-op, CoIoT has no authentication.
+func (c *Client) SetBasicAuth(*retryablehttp.Request) error {
+	return nil
+}
+
+func (c *Client) Do(req *retryablehttp.Request, v interface{}) (*contracts.Response, error) {
+	if req.Request == nil {
+		return nil, fmt.Errorf("empty request")
+	}
+	if req.Method != http.MethodGet {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedMethod, req.Method)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), c.timeout)
+	defer cancel()
+
+	msg := &Message{
+		Type:      Confirmable,
+		Code:      CodeGet,
+		MessageID: uint16(atomic.AddUint32(&c.messageID, 1)),
+		Token:     make([]byte, 4),
+		Options:   PathOptions(req.URL.Path),
+	}
+	rand.Read(msg.Token)
+	for _, q := range strings.Split(req.URL.RawQuery, "&") {
+		if q != "" {
+			msg.Options = append(msg.Options, Option{Number: OptionURIQuery, Value: []byte(q)})
+		}
+	}
+
+	start := time.Now()
+	reply, attempts, err := c.exchange(ctx, req.URL.Host, msg)
+	latency := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &http.Response{
+		Status:        codeString(reply.Code),
+		StatusCode:    statusCode(reply.Code),
+		Proto:         "CoAP/1",
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(reply.Payload)),
+		ContentLength: int64(len(reply.Payload)),
+		Request:       req.Request,
+	}
+	if reply.Code>>5 != 2 {
+		return nil, &StatusError{Code: reply.Code, StatusCode: resp.StatusCode, Payload: reply.Payload}
+	}
+	if len(reply.Payload) > 0 {
+		if err := json.Unmarshal(reply.Payload, v); err != nil {
+			return nil, err
+		}
+	}
+	return &contracts.Response{
+		Response: resp,
+		Latency:  latency,
+		Attempts: attempts,
+		FinalURL: req.URL,
+		RawBody:  reply.Payload,
+	}, nil
+}
+
+// exchange sends a confirmable request and waits for its response, either
+// piggybacked on the acknowledgement or sent separately afterwards.
+func (c *Client) exchange(ctx context.Context, host string, msg *Message) (*Message, int, error) {
+	packet, err := msg.Marshal()
+	if err != nil {
+		return nil, 0, err
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "udp", host)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	attempts := 0
+	acked := false
+	wait := c.ackTimeout
+	buf := make([]byte, 64*1024)
+	for {
+		if !acked {
+			if attempts > c.maxRetransmit {
+				return nil, attempts, ErrTimeout
+			}
+			if _, err := conn.Write(packet); err != nil {
+				return nil, attempts, err
+			}
+			attempts++
+			conn.SetReadDeadline(time.Now().Add(wait))
+			wait *= 2
+		}
+
+		n, err := conn.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return nil, attempts, ErrTimeout
+				}
+				return nil, attempts, ctx.Err()
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return nil, attempts, err
+		}
+		reply, err := ParseMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		if reply.Type == Reset && reply.MessageID == msg.MessageID {
+			return nil, attempts, ErrReset
+		}
+		if reply.Type == Acknowledgement && reply.MessageID == msg.MessageID {
+			if reply.Code != CodeEmpty {
+				return reply, attempts, nil
+			}
+			// The response comes later in its own message.
+			acked = true
+			conn.SetReadDeadline(time.Time{})
+			continue
+		}
+		if !bytes.Equal(reply.Token, msg.Token) || reply.Code == CodeEmpty {
+			continue
+		}
+		if reply.Type == Confirmable {
+			ack, _ := (&Message{Type: Acknowledgement, MessageID: reply.MessageID}).Marshal()
+			conn.Write(ack)
+		}
+		return reply, attempts, nil
+	}
+}
+
+// statusCode maps a CoAP response code to the closest HTTP status.
+func statusCode(code uint8) int {
+	class, detail := int(code>>5), int(code&0x1f)
+	switch {
+	case class == 2:
+		return http.StatusOK
+	case class == 4 && detail == 1:
+		return http.StatusUnauthorized
+	case class == 4 && detail == 4:
+		return http.StatusNotFound
+	case class == 4 && detail == 5:
+		return http.StatusMethodNotAllowed
+	case class == 4:
+		return http.StatusBadRequest
+	case class == 5:
+		return http.StatusInternalServerError
+	}
+	return 0
+}
+
+func codeString(code uint8) string {
+	return fmt.Sprintf("%d.%02d", code>>5, code&0x1f)
+}
@@ -0,0 +1,200 @@
+package coiot
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setupServer answers CoAP requests on loopback with handle, a nil reply
+// drops the request.
+func setupServer(t *testing.T, handle func(conn net.PacketConn, addr net.Addr, req *Message) *Message) string {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			req, err := ParseMessage(buf[:n])
+			if err != nil {
+				continue
+			}
+			if reply := handle(conn, addr, req); reply != nil {
+				b, _ := reply.Marshal()
+				conn.WriteTo(b, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestClientDo(t *testing.T) {
+	var requests int32
+	host := setupServer(t, func(_ net.PacketConn, _ net.Addr, req *Message) *Message {
+		assert.Equal(t, Confirmable, req.Type)
+		assert.Equal(t, "cit/s", req.Path())
+		// Drop the first transmission to force a retransmission.
+		if atomic.AddInt32(&requests, 1) == 1 {
+			return nil
+		}
+		return &Message{
+			Type:      Acknowledgement,
+			Code:      CodeContent,
+			MessageID: req.MessageID,
+			Token:     req.Token,
+			Payload:   []byte(`{"G":[[0,1101,1]]}`),
+		}
+	})
+
+	c, err := NewClient(ClientOptions{Hostname: host, AckTimeout: 50 * time.Millisecond})
+	assert.NoError(t, err)
+	req, err := c.NewRequest(http.MethodGet, "/cit/s", nil)
+	assert.NoError(t, err)
+
+	var status Status
+	resp, err := c.Do(req, &status)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.Attempts)
+	assert.Equal(t, "coap://"+host+"/cit/s", resp.FinalURL.String())
+	assert.Equal(t, []Sensor{{Channel: 0, ID: 1101, Value: float64(1)}}, status.Sensors)
+}
+
+func TestClientSeparateResponse(t *testing.T) {
+	acked := make(chan struct{})
+	host := setupServer(t, func(conn net.PacketConn, addr net.Addr, req *Message) *Message {
+		if req.Type == Acknowledgement {
+			close(acked)
+			return nil
+		}
+		ack, _ := (&Message{Type: Acknowledgement, MessageID: req.MessageID}).Marshal()
+		conn.WriteTo(ack, addr)
+		return &Message{
+			Type:      Confirmable,
+			Code:      CodeContent,
+			MessageID: req.MessageID + 100,
+			Token:     req.Token,
+			Payload:   []byte(`{"blk":[{"I":1,"D":"relay_0"}],"sen":[]}`),
+		}
+	})
+
+	c, err := NewClient(ClientOptions{Hostname: host, AckTimeout: 50 * time.Millisecond})
+	assert.NoError(t, err)
+	req, err := c.NewRequest(http.MethodGet, "/cit/d", nil)
+	assert.NoError(t, err)
+
+	var description Description
+	resp, err := c.Do(req, &description)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, resp.Attempts)
+	assert.Equal(t, []Block{{ID: 1, Name: "relay_0"}}, description.Blocks)
+	select {
+	case <-acked:
+	case <-time.After(5 * time.Second):
+		t.Fatal("separate response wasn't acknowledged")
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	type test struct {
+		title  string
+		handle func(net.PacketConn, net.Addr, *Message) *Message
+		ctx    func() context.Context
+		want   error
+	}
+	tests := []test{
+		{
+			title:  "Device never answers",
+			handle: func(net.PacketConn, net.Addr, *Message) *Message { return nil },
+			want:   ErrTimeout,
+		},
+		{
+			title: "Device resets the request",
+			handle: func(_ net.PacketConn, _ net.Addr, req *Message) *Message {
+				return &Message{Type: Reset, MessageID: req.MessageID}
+			},
+			want: ErrReset,
+		},
+		{
+			title:  "Context canceled",
+			handle: func(net.PacketConn, net.Addr, *Message) *Message { return nil },
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			want: context.Canceled,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			host := setupServer(t, tc.handle)
+			c, err := NewClient(ClientOptions{Hostname: host, AckTimeout: 10 * time.Millisecond, MaxRetransmit: 2})
+			assert.NoError(t, err)
+			req, err := c.NewRequest(http.MethodGet, "/cit/s", nil)
+			assert.NoError(t, err)
+			if tc.ctx != nil {
+				req = req.WithContext(tc.ctx())
+			}
+			_, err = c.Do(req, &Status{})
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestClientStatusError(t *testing.T) {
+	type test struct {
+		title          string
+		code           uint8
+		wantStatusCode int
+		wantMessage    string
+	}
+	tests := []test{
+		{title: "Unauthorized", code: 0x81, wantStatusCode: http.StatusUnauthorized, wantMessage: "coap request failed with code 4.01"},
+		{title: "Not found", code: 0x84, wantStatusCode: http.StatusNotFound, wantMessage: "coap request failed with code 4.04"},
+		{title: "Internal server error", code: 0xa0, wantStatusCode: http.StatusInternalServerError, wantMessage: "coap request failed with code 5.00"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			host := setupServer(t, func(_ net.PacketConn, _ net.Addr, req *Message) *Message {
+				return &Message{
+					Type:      Acknowledgement,
+					Code:      tc.code,
+					MessageID: req.MessageID,
+					Token:     req.Token,
+					Payload:   []byte("failed"),
+				}
+			})
+			c, err := NewClient(ClientOptions{Hostname: host, AckTimeout: 50 * time.Millisecond})
+			assert.NoError(t, err)
+			req, err := c.NewRequest(http.MethodGet, "/cit/s", nil)
+			assert.NoError(t, err)
+
+			resp, err := c.Do(req, &Status{})
+			assert.Nil(t, resp)
+			var statusErr *StatusError
+			assert.ErrorAs(t, err, &statusErr)
+			assert.Equal(t, tc.code, statusErr.Code)
+			assert.Equal(t, tc.wantStatusCode, statusErr.StatusCode)
+			assert.Equal(t, []byte("failed"), statusErr.Payload)
+			assert.EqualError(t, err, tc.wantMessage)
+		})
+	}
+}
+
+func TestClientUnsupportedMethod(t *testing.T) {
+	c, err := NewClient(ClientOptions{Hostname: "192.168.1.10"})
+	assert.NoError(t, err)
+	assert.Equal(t, "coap://192.168.1.10:5683", c.BaseURL.String())
+	_, err = c.NewRequest(http.MethodPost, "/cit/s", nil)
+	assert.ErrorIs(t, err, ErrUnsupportedMethod)
+}
@@ -0,0 +1,207 @@
+package coiot
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrMalformedMessage is returned when a frame isn't valid CoAP.
+var ErrMalformedMessage = errors.New("malformed coap message")
+
+// MessageType is the CoAP message type.
+type MessageType uint8
+
+const (
+	Confirmable MessageType = iota
+	NonConfirmable
+	Acknowledgement
+	Reset
+)
+
+// Codes used by CoIoT. Shelly pushes status with the non standard 0.30 code.
+const (
+	CodeEmpty   uint8 = 0x00
+	CodeGet     uint8 = 0x01
+	CodeContent uint8 = 0x45
+	CodeStatus  uint8 = 0x1e
+)
+
+// Options used by CoIoT, the 33xx and 34xx ones are Shelly specific.
+const (
+	OptionURIPath  uint16 = 11
+	OptionURIQuery uint16 = 15
+	OptionDevice   uint16 = 3332
+	OptionValidity uint16 = 3412
+	OptionSerial   uint16 = 3420
+)
+
+// Option is a CoAP option.
+type Option struct {
+	Number uint16
+	Value  []byte
+}
+
+// Message is a CoAP frame.
+type Message struct {
+	Type      MessageType
+	Code      uint8
+	MessageID uint16
+	Token     []byte
+	Options   []Option
+	Payload   []byte
+}
+
+// Option returns the value of the first option with the given number.
+func (m *Message) Option(number uint16) ([]byte, bool) {
+	for _, o := range m.Options {
+		if o.Number == number {
+			return o.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Path joins the URI path options, e.g. cit/s.
+func (m *Message) Path() string {
+	path := ""
+	for _, o := range m.Options {
+		if o.Number != OptionURIPath {
+			continue
+		}
+		if path != "" {
+			path += "/"
+		}
+		path += string(o.Value)
+	}
+	return path
+}
+
+// ParseMessage decodes a CoAP frame.
+func ParseMessage(b []byte) (*Message, error) {
+	if len(b) < 4 {
+		return nil, fmt.Errorf("%w: short header", ErrMalformedMessage)
+	}
+	if b[0]>>6 != 1 {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrMalformedMessage, b[0]>>6)
+	}
+	tkl := int(b[0] & 0x0f)
+	if tkl > 8 || len(b) < 4+tkl {
+		return nil, fmt.Errorf("%w: invalid token length", ErrMalformedMessage)
+	}
+	m := &Message{
+		Type:      MessageType(b[0] >> 4 & 0x03),
+		Code:      b[1],
+		MessageID: binary.BigEndian.Uint16(b[2:4]),
+	}
+	if tkl > 0 {
+		m.Token = append([]byte(nil), b[4:4+tkl]...)
+	}
+
+	rest := b[4+tkl:]
+	number := 0
+	for len(rest) > 0 {
+		if rest[0] == 0xff {
+			if len(rest) == 1 {
+				return nil, fmt.Errorf("%w: empty payload after marker", ErrMalformedMessage)
+			}
+			m.Payload = append([]byte(nil), rest[1:]...)
+			break
+		}
+		delta, length := int(rest[0]>>4), int(rest[0]&0x0f)
+		rest = rest[1:]
+		var err error
+		if delta, rest, err = optionNibble(delta, rest); err != nil {
+			return nil, err
+		}
+		if length, rest, err = optionNibble(length, rest); err != nil {
+			return nil, err
+		}
+		if len(rest) < length {
+			return nil, fmt.Errorf("%w: option value overflows the frame", ErrMalformedMessage)
+		}
+		number += delta
+		if number > 0xffff {
+			return nil, fmt.Errorf("%w: option number overflows", ErrMalformedMessage)
+		}
+		m.Options = append(m.Options, Option{Number: uint16(number), Value: append([]byte(nil), rest[:length]...)})
+		rest = rest[length:]
+	}
+	return m, nil
+}
+
+func optionNibble(v int, rest []byte) (int, []byte, error) {
+	switch v {
+	case 13:
+		if len(rest) < 1 {
+			return 0, nil, fmt.Errorf("%w: truncated option", ErrMalformedMessage)
+		}
+		return int(rest[0]) + 13, rest[1:], nil
+	case 14:
+		if len(rest) < 2 {
+			return 0, nil, fmt.Errorf("%w: truncated option", ErrMalformedMessage)
+		}
+		return int(binary.BigEndian.Uint16(rest)) + 269, rest[2:], nil
+	case 15:
+		return 0, nil, fmt.Errorf("%w: reserved option nibble", ErrMalformedMessage)
+	}
+	return v, rest, nil
+}
+
+// Marshal encodes the message, options are sorted by number.
+func (m *Message) Marshal() ([]byte, error) {
+	if len(m.Token) > 8 {
+		return nil, fmt.Errorf("%w: token longer than 8 bytes", ErrMalformedMessage)
+	}
+	b := []byte{1<<6 | byte(m.Type&0x03)<<4 | byte(len(m.Token)), m.Code, 0, 0}
+	binary.BigEndian.PutUint16(b[2:], m.MessageID)
+	b = append(b, m.Token...)
+
+	options := append([]Option(nil), m.Options...)
+	sort.SliceStable(options, func(i, j int) bool { return options[i].Number < options[j].Number })
+	last := 0
+	for _, o := range options {
+		delta, length := int(o.Number)-last, len(o.Value)
+		last = int(o.Number)
+		dn, dext := encodeNibble(delta)
+		ln, lext := encodeNibble(length)
+		b = append(b, dn<<4|ln)
+		b = append(b, dext...)
+		b = append(b, lext...)
+		b = append(b, o.Value...)
+	}
+	if len(m.Payload) > 0 {
+		b = append(b, 0xff)
+		b = append(b, m.Payload...)
+	}
+	return b, nil
+}
+
+func encodeNibble(v int) (byte, []byte) {
+	switch {
+	case v < 13:
+		return byte(v), nil
+	case v < 269:
+		return 13, []byte{byte(v - 13)}
+	}
+	ext := make([]byte, 2)
+	binary.BigEndian.PutUint16(ext, uint16(v-269))
+	return 14, ext
+}
+
+// PathOptions splits a path such as cit/d into URI path options.
+func PathOptions(path string) []Option {
+	var options []Option
+	start := 0
+	for i := 0; i <= len(path); i++ {
+		if i < len(path) && path[i] != '/' {
+			continue
+		}
+		if i > start {
+			options = append(options, Option{Number: OptionURIPath, Value: []byte(path[start:i])})
+		}
+		start = i + 1
+	}
+	return options
+}
@@ -0,0 +1,83 @@
+package coiot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageRoundTrip(t *testing.T) {
+	type test struct {
+		title string
+		input Message
+	}
+	tests := []test{
+		{
+			title: "Get request with token and path",
+			input: Message{
+				Type:      Confirmable,
+				Code:      CodeGet,
+				MessageID: 0x1234,
+				Token:     []byte{1, 2, 3, 4},
+				Options:   PathOptions("cit/d"),
+			},
+		},
+		{
+			title: "Status push with Shelly options",
+			input: Message{
+				Type:      NonConfirmable,
+				Code:      CodeStatus,
+				MessageID: 7,
+				Options: append(PathOptions("cit/s"),
+					Option{Number: OptionDevice, Value: []byte("SHSW-1#A4CF12F45678#2")},
+					Option{Number: OptionValidity, Value: []byte{0x01, 0x2c}},
+					Option{Number: OptionSerial, Value: []byte{0x00, 0x2a}},
+				),
+				Payload: []byte(`{"G":[[0,112,1]]}`),
+			},
+		},
+		{
+			title: "Long option value",
+			input: Message{
+				Type:    Acknowledgement,
+				Code:    CodeContent,
+				Options: []Option{{Number: 300, Value: make([]byte, 400)}},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			b, err := tc.input.Marshal()
+			assert.NoError(t, err)
+			got, err := ParseMessage(b)
+			assert.NoError(t, err)
+			assert.Equal(t, &tc.input, got)
+		})
+	}
+}
+
+func TestParseMessageErrors(t *testing.T) {
+	type test struct {
+		title string
+		input []byte
+	}
+	tests := []test{
+		{title: "Short header", input: []byte{0x40, 0x01}},
+		{title: "Wrong version", input: []byte{0x80, 0x01, 0, 0}},
+		{title: "Token overflows", input: []byte{0x44, 0x01, 0, 0, 1}},
+		{title: "Reserved option nibble", input: []byte{0x40, 0x01, 0, 0, 0xf1, 0}},
+		{title: "Option value overflows", input: []byte{0x40, 0x01, 0, 0, 0xb4, 'c'}},
+		{title: "Marker without payload", input: []byte{0x40, 0x01, 0, 0, 0xff}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			_, err := ParseMessage(tc.input)
+			assert.ErrorIs(t, err, ErrMalformedMessage)
+		})
+	}
+}
+
+func TestMessagePath(t *testing.T) {
+	m := Message{Options: PathOptions("/cit/s")}
+	assert.Equal(t, "cit/s", m.Path())
+}
@@ -0,0 +1,152 @@
+package coiot
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Scheme is the CoIoT sensor id scheme of a device. Firmware before 1.8 uses
+// three digit ids (112, 111...) and infers units from the sensor type, later
+// firmware uses four digit ids (1101, 4101...) and sends the unit.
+type Scheme int
+
+const (
+	SchemeV1 Scheme = iota + 1
+	SchemeV2
+)
+
+// IntList decodes a JSON number or an array of numbers.
+type IntList []int
+
+func (l *IntList) UnmarshalJSON(b []byte) error {
+	var one int
+	if err := json.Unmarshal(b, &one); err == nil {
+		*l = IntList{one}
+		return nil
+	}
+	var many []int
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
+// StringList decodes a JSON string or an array of strings.
+type StringList []string
+
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	var one string
+	if err := json.Unmarshal(b, &one); err == nil {
+		*l = StringList{one}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(b, &many); err != nil {
+		return err
+	}
+	*l = many
+	return nil
+}
+
+// Block is a group of sensors, usually a channel or the device itself.
+type Block struct {
+	ID   int    `json:"I"`
+	Name string `json:"D"`
+}
+
+// SensorDescription describes a sensor id of the status pushes.
+type SensorDescription struct {
+	ID    int        `json:"I"`
+	Type  string     `json:"T"`
+	Name  string     `json:"D"`
+	Unit  string     `json:"U,omitempty"`
+	Range StringList `json:"R,omitempty"`
+	Links IntList    `json:"L"`
+}
+
+// Description is the /cit/d document of a device.
+type Description struct {
+	Blocks  []Block             `json:"blk"`
+	Sensors []SensorDescription `json:"sen"`
+}
+
+// Scheme detects the sensor id scheme from the described ids.
+func (d *Description) Scheme() Scheme {
+	for _, s := range d.Sensors {
+		if s.ID >= 1000 {
+			return SchemeV2
+		}
+	}
+	return SchemeV1
+}
+
+// Reading is a status value joined with its description.
+type Reading struct {
+	Sensor
+	Block Block
+	Name  string
+	Type  string
+	Unit  string
+	Known bool
+}
+
+// v1Units are the units implied by the sensor types of the v1 scheme.
+var v1Units = map[string]string{
+	"P": "W",
+	"E": "Wmin",
+	"I": "A",
+	"V": "V",
+	"H": "%",
+	"B": "%",
+	"L": "lux",
+}
+
+// Decode joins the sensors of a status with the description. Sensors missing
+// from the description are kept with Known set to false.
+func (d *Description) Decode(status *Status) []Reading {
+	scheme := d.Scheme()
+	sensors := make(map[int]SensorDescription, len(d.Sensors))
+	for _, s := range d.Sensors {
+		sensors[s.ID] = s
+	}
+	blocks := make(map[int]Block, len(d.Blocks))
+	for _, b := range d.Blocks {
+		blocks[b.ID] = b
+	}
+
+	readings := make([]Reading, 0, len(status.Sensors))
+	for _, sensor := range status.Sensors {
+		reading := Reading{Sensor: sensor}
+		desc, ok := sensors[sensor.ID]
+		if ok {
+			reading.Known = true
+			reading.Name = desc.Name
+			reading.Type = desc.Type
+			reading.Unit = unit(scheme, desc)
+			if len(desc.Links) > 0 {
+				link := desc.Links[0]
+				if sensor.Channel > 0 && sensor.Channel < len(desc.Links) {
+					link = desc.Links[sensor.Channel]
+				}
+				reading.Block = blocks[link]
+			}
+		}
+		readings = append(readings, reading)
+	}
+	return readings
+}
+
+func unit(scheme Scheme, desc SensorDescription) string {
+	if desc.Unit != "" || scheme == SchemeV2 {
+		return desc.Unit
+	}
+	if desc.Type == "T" {
+		name := strings.ToUpper(desc.Name)
+		if strings.HasSuffix(name, " F") || strings.HasSuffix(name, "TF") {
+			return "F"
+		}
+		return "C"
+	}
+	return v1Units[desc.Type]
+}
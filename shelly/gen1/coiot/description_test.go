@@ -0,0 +1,90 @@
+package coiot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const v1Description = `{
+	"blk": [{"I": 0, "D": "Relay0"}, {"I": 1, "D": "Relay1"}, {"I": 2, "D": "Device"}],
+	"sen": [
+		{"I": 111, "T": "P", "D": "Power", "R": "0/3500", "L": 0},
+		{"I": 112, "T": "S", "D": "State", "R": "0/1", "L": 0},
+		{"I": 122, "T": "S", "D": "State", "R": "0/1", "L": 1},
+		{"I": 113, "T": "T", "D": "Temperature C", "R": "-40/300", "L": 2},
+		{"I": 114, "T": "T", "D": "Temperature F", "R": "-40/572", "L": 2},
+		{"I": 118, "T": "S", "D": "Switch", "L": [0, 1]}
+	]
+}`
+
+const v2Description = `{
+	"blk": [{"I": 1, "D": "relay_0"}, {"I": 2, "D": "device"}],
+	"sen": [
+		{"I": 1101, "T": "S", "D": "output", "R": "0/1", "L": 1},
+		{"I": 4101, "T": "P", "D": "power", "U": "W", "R": ["0/2300", "-1"], "L": 1},
+		{"I": 3104, "T": "T", "D": "deviceTemp", "U": "C", "R": ["-40/300", "999"], "L": 2}
+	]
+}`
+
+func TestDescriptionDecode(t *testing.T) {
+	type reading struct {
+		block string
+		name  string
+		unit  string
+		value interface{}
+	}
+	type test struct {
+		title       string
+		description string
+		status      string
+		scheme      Scheme
+		want        []reading
+	}
+	tests := []test{
+		{
+			title:       "Old id scheme infers units",
+			description: v1Description,
+			status:      `{"G":[[0,111,40.5],[0,112,1],[0,122,0],[0,113,38.2],[0,114,100.8],[1,118,1]]}`,
+			scheme:      SchemeV1,
+			want: []reading{
+				{block: "Relay0", name: "Power", unit: "W", value: 40.5},
+				{block: "Relay0", name: "State", value: float64(1)},
+				{block: "Relay1", name: "State", value: float64(0)},
+				{block: "Device", name: "Temperature C", unit: "C", value: 38.2},
+				{block: "Device", name: "Temperature F", unit: "F", value: 100.8},
+				{block: "Relay1", name: "Switch", value: float64(1)},
+			},
+		},
+		{
+			title:       "V2 id scheme uses the described units",
+			description: v2Description,
+			status:      `{"G":[[0,1101,1],[0,4101,12.5],[0,3104,41.2],[0,9999,"x"]]}`,
+			scheme:      SchemeV2,
+			want: []reading{
+				{block: "relay_0", name: "output", value: float64(1)},
+				{block: "relay_0", name: "power", unit: "W", value: 12.5},
+				{block: "device", name: "deviceTemp", unit: "C", value: 41.2},
+				{value: "x"},
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			var description Description
+			assert.NoError(t, json.Unmarshal([]byte(tc.description), &description))
+			var status Status
+			assert.NoError(t, json.Unmarshal([]byte(tc.status), &status))
+			assert.Equal(t, tc.scheme, description.Scheme())
+
+			readings := description.Decode(&status)
+			got := make([]reading, 0, len(readings))
+			for _, r := range readings {
+				got = append(got, reading{block: r.Block.Name, name: r.Name, unit: r.Unit, value: r.Value})
+				assert.Equal(t, r.Name != "", r.Known)
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package coiot
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MulticastAddress is the group Gen1 devices push their status to.
+const MulticastAddress = "224.0.1.187:5683"
+
+// ListenOptions configures a CoIoT listener.
+type ListenOptions struct {
+	// Conn receives the status pushes. Defaults to a multicast listener on
+	// MulticastAddress, use a unicast listener on port 5683 for devices
+	// configured with a coiot_peer. A supplied Conn is left open by Close.
+	Conn net.PacketConn
+	// Interface is the interface joined to the multicast group, nil lets the
+	// system pick one.
+	Interface *net.Interface
+	// Buffer is the size of every subscription channel. Defaults to 16.
+	Buffer int
+}
+
+type subscription struct {
+	device string
+	ch     chan Status
+}
+
+// Listener decodes CoIoT status pushes and delivers them to the subscribers
+// of each device. Repeated pushes with the same serial are dropped.
+type Listener struct {
+	conn     net.PacketConn
+	ownsConn bool
+	buffer   int
+	mu       sync.Mutex
+	subs     map[*subscription]struct{}
+	serials  map[string]uint64
+	closed   bool
+	done     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+// Listen starts a listener, it stops when ctx is canceled or Close is called.
+func Listen(ctx context.Context, opts ListenOptions) (*Listener, error) {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 16
+	}
+	owned := opts.Conn == nil
+	if owned {
+		group, err := net.ResolveUDPAddr("udp4", MulticastAddress)
+		if err != nil {
+			return nil, err
+		}
+		opts.Conn, err = net.ListenMulticastUDP("udp4", opts.Interface, group)
+		if err != nil {
+			return nil, err
+		}
+	}
+	l := &Listener{
+		conn:     opts.Conn,
+		ownsConn: owned,
+		buffer:   opts.Buffer,
+		subs:     map[*subscription]struct{}{},
+		serials:  map[string]uint64{},
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go l.read()
+	go func() {
+		select {
+		case <-ctx.Done():
+			l.Close()
+		case <-l.done:
+		}
+	}()
+	return l, nil
+}
+
+// Subscribe returns the status pushes of a device, an empty id subscribes to
+// every device. Pushes are dropped while the channel is full. The returned
+// func cancels the subscription.
+func (l *Listener) Subscribe(deviceID string) (<-chan Status, func()) {
+	sub := &subscription{device: strings.ToUpper(deviceID), ch: make(chan Status, l.buffer)}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	l.subs[sub] = struct{}{}
+	return sub.ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.subs[sub]; ok {
+			delete(l.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Close stops the listener and closes the subscriptions.
+func (l *Listener) Close() error {
+	return l.close(true)
+}
+
+// close stops the listener, wait is false when called by the reader itself.
+func (l *Listener) close(wait bool) error {
+	var err error
+	l.once.Do(func() {
+		close(l.done)
+		if l.ownsConn {
+			err = l.conn.Close()
+		} else {
+			// Unblock the reader without closing a connection we don't own,
+			// then clear the deadline so the caller can keep using it.
+			l.conn.SetReadDeadline(time.Now())
+			if wait {
+				<-l.stopped
+			}
+			l.conn.SetReadDeadline(time.Time{})
+		}
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.closed = true
+		for sub := range l.subs {
+			delete(l.subs, sub)
+			close(sub.ch)
+		}
+	})
+	return err
+}
+
+func (l *Listener) read() {
+	defer close(l.stopped)
+	buf := make([]byte, 2048)
+	for {
+		n, addr, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-l.done:
+			default:
+				l.close(false)
+			}
+			return
+		}
+		m, err := ParseMessage(buf[:n])
+		if err != nil {
+			continue
+		}
+		status, err := DecodeStatus(m)
+		if err != nil {
+			continue
+		}
+		status.Source = addr
+		status.Received = time.Now()
+		l.dispatch(*status)
+	}
+}
+
+func (l *Listener) dispatch(status Status) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	id := strings.ToUpper(status.Device.ID)
+	if serial, ok := l.serials[id]; ok && serial == status.Serial {
+		return
+	}
+	l.serials[id] = status.Serial
+	for sub := range l.subs {
+		if sub.device != "" && sub.device != id {
+			continue
+		}
+		select {
+		case sub.ch <- status:
+		default:
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package coiot
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func statusFrame(t *testing.T, device string, serial byte, payload string) []byte {
+	m := Message{
+		Type: NonConfirmable,
+		Code: CodeStatus,
+		Options: append(PathOptions("cit/s"),
+			Option{Number: OptionDevice, Value: []byte(device)},
+			Option{Number: OptionValidity, Value: []byte{0x01, 0x2c}},
+			Option{Number: OptionSerial, Value: []byte{serial}},
+		),
+		Payload: []byte(payload),
+	}
+	b, err := m.Marshal()
+	assert.NoError(t, err)
+	return b
+}
+
+func TestDecodeStatus(t *testing.T) {
+	m, err := ParseMessage(statusFrame(t, "SHSW-25#A4CF12F45678#2", 42, `{"G":[[0,112,1],[1,111,12.5],[0,118,"close"]]}`))
+	assert.NoError(t, err)
+
+	status, err := DecodeStatus(m)
+	assert.NoError(t, err)
+	assert.Equal(t, DeviceHeader{Type: "SHSW-25", ID: "A4CF12F45678", Revision: 2}, status.Device)
+	assert.Equal(t, uint64(42), status.Serial)
+	assert.Equal(t, 30*time.Second, status.Validity)
+	assert.Len(t, status.Sensors, 3)
+
+	power, ok := status.Sensor(111)
+	assert.True(t, ok)
+	assert.Equal(t, 1, power.Channel)
+	value, ok := power.Float()
+	assert.True(t, ok)
+	assert.Equal(t, 12.5, value)
+
+	state, _ := status.Sensor(118)
+	assert.Equal(t, "close", state.Value)
+}
+
+func TestDecodeStatusErrors(t *testing.T) {
+	type test struct {
+		title string
+		input Message
+	}
+	tests := []test{
+		{title: "Not a status message", input: Message{Code: CodeGet, Options: PathOptions("cit/d")}},
+		{title: "Missing device option", input: Message{Code: CodeStatus, Payload: []byte(`{"G":[]}`)}},
+		{title: "Invalid device option", input: Message{Code: CodeStatus, Options: []Option{{Number: OptionDevice, Value: []byte("SHSW-1")}}}},
+		{title: "Invalid payload", input: Message{Code: CodeStatus, Options: []Option{{Number: OptionDevice, Value: []byte("SHSW-1#A4CF12F45678#2")}}, Payload: []byte("{")}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			_, err := DecodeStatus(&tc.input)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestListener(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	l, err := Listen(ctx, ListenOptions{Conn: conn})
+	assert.NoError(t, err)
+	all, _ := l.Subscribe("")
+	device, unsubscribe := l.Subscribe("a4cf12f45678")
+	defer unsubscribe()
+
+	sender, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+	send := func(b []byte) {
+		_, err := sender.WriteTo(b, conn.LocalAddr())
+		assert.NoError(t, err)
+	}
+	send(statusFrame(t, "SHSW-1#A4CF12F45678#2", 1, `{"G":[[0,112,1]]}`))
+	send(statusFrame(t, "SHSW-1#A4CF12F45678#2", 1, `{"G":[[0,112,1]]}`))
+	send(statusFrame(t, "SHDM-2#B4CF12F45678#2", 1, `{"G":[[0,121,50]]}`))
+	send(statusFrame(t, "SHSW-1#A4CF12F45678#2", 2, `{"G":[[0,112,0]]}`))
+
+	receive := func(ch <-chan Status) Status {
+		select {
+		case s := <-ch:
+			return s
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a status")
+		}
+		return Status{}
+	}
+	first := receive(device)
+	assert.Equal(t, uint64(1), first.Serial)
+	assert.Equal(t, sender.LocalAddr().String(), first.Source.String())
+	second := receive(device)
+	assert.Equal(t, uint64(2), second.Serial)
+
+	ids := []string{receive(all).Device.ID, receive(all).Device.ID, receive(all).Device.ID}
+	assert.Equal(t, []string{"A4CF12F45678", "B4CF12F45678", "A4CF12F45678"}, ids)
+
+	cancel()
+	_, ok := <-device
+	for ok {
+		_, ok = <-device
+	}
+	assert.False(t, ok)
+}
+
+func TestListenerCloseKeepsSuppliedConn(t *testing.T) {
+	conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	l, err := Listen(context.Background(), ListenOptions{Conn: conn})
+	assert.NoError(t, err)
+	assert.NoError(t, l.Close())
+
+	sender, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer sender.Close()
+	_, err = sender.WriteTo([]byte("ping"), conn.LocalAddr())
+	assert.NoError(t, err)
+
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	buf := make([]byte, 16)
+	n, _, err := conn.ReadFrom(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(buf[:n]))
+}
@@ -0,0 +1,154 @@
+package coiot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotStatus is returned when a message isn't a CoIoT status push.
+var ErrNotStatus = errors.New("message is not a coiot status")
+
+// DeviceHeader is the content of the Shelly device option, e.g.
+// SHSW-1#A4CF12F45678#2.
+type DeviceHeader struct {
+	Type     string
+	ID       string
+	Revision int
+}
+
+// ParseDeviceHeader decodes the device option value.
+func ParseDeviceHeader(value string) (DeviceHeader, error) {
+	parts := strings.Split(value, "#")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" {
+		return DeviceHeader{}, fmt.Errorf("invalid coiot device header %q", value)
+	}
+	revision, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return DeviceHeader{}, fmt.Errorf("invalid coiot device header %q: %w", value, err)
+	}
+	return DeviceHeader{Type: parts[0], ID: parts[1], Revision: revision}, nil
+}
+
+// Sensor is an entry of the G array: channel, sensor id and value.
+type Sensor struct {
+	Channel int
+	ID      int
+	Value   interface{}
+}
+
+// UnmarshalJSON decodes the [channel, id, value] triplet.
+func (s *Sensor) UnmarshalJSON(b []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	if len(raw) != 3 {
+		return fmt.Errorf("invalid coiot sensor %s", b)
+	}
+	if err := json.Unmarshal(raw[0], &s.Channel); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw[1], &s.ID); err != nil {
+		return err
+	}
+	return json.Unmarshal(raw[2], &s.Value)
+}
+
+// Float returns the value as a number, booleans and numeric strings are
+// converted as well.
+func (s Sensor) Float() (float64, bool) {
+	switch v := s.Value.(type) {
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// Status is a status push of a device, or the /cit/s document which only
+// holds the sensors.
+type Status struct {
+	Device   DeviceHeader  `json:"-"`
+	Serial   uint64        `json:"-"`
+	Validity time.Duration `json:"-"`
+	Sensors  []Sensor      `json:"G"`
+	Source   net.Addr      `json:"-"`
+	Received time.Time     `json:"-"`
+}
+
+// Sensor returns the sensor with the given id.
+func (s *Status) Sensor(id int) (Sensor, bool) {
+	for _, sensor := range s.Sensors {
+		if sensor.ID == id {
+			return sensor, true
+		}
+	}
+	return Sensor{}, false
+}
+
+// DecodeStatus decodes a CoIoT status message.
+func DecodeStatus(m *Message) (*Status, error) {
+	if m.Code != CodeStatus && m.Path() != "cit/s" {
+		return nil, ErrNotStatus
+	}
+	device, ok := m.Option(OptionDevice)
+	if !ok {
+		return nil, fmt.Errorf("%w: missing device option", ErrNotStatus)
+	}
+	header, err := ParseDeviceHeader(string(device))
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Device: header}
+	if serial, ok := m.Option(OptionSerial); ok {
+		status.Serial = optionUint(serial)
+	}
+	if validity, ok := m.Option(OptionValidity); ok {
+		status.Validity = decodeValidity(optionUint(validity))
+	}
+	sensors, err := decodeSensors(m.Payload)
+	if err != nil {
+		return nil, err
+	}
+	status.Sensors = sensors
+	return status, nil
+}
+
+func decodeSensors(payload []byte) ([]Sensor, error) {
+	var body struct {
+		G []Sensor `json:"G"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("invalid coiot payload: %w", err)
+	}
+	return body.G, nil
+}
+
+func optionUint(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// decodeValidity follows the CoIoT spec: an even value is in tenths of a
+// second, an odd one in units of four seconds.
+func decodeValidity(v uint64) time.Duration {
+	if v&1 == 0 {
+		return time.Duration(v) * 100 * time.Millisecond
+	}
+	return time.Duration(v) * 4 * time.Second
+}
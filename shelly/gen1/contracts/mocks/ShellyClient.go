@@ -70,6 +70,32 @@ func (_m *ShellyClient) NewRequest(method string, endpoint string, opts interfac
 	return r0, r1
 }
 
+// NewRequestWithContext provides a mock function with given fields: ctx, method, endpoint, opts
+func (_m *ShellyClient) NewRequestWithContext(ctx context.Context, method string, endpoint string, opts interface{}) (*retryablehttp.Request, error) {
+	ret := _m.Called(ctx, method, endpoint, opts)
+
+	var r0 *retryablehttp.Request
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, interface{}) (*retryablehttp.Request, error)); ok {
+		return rf(ctx, method, endpoint, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, interface{}) *retryablehttp.Request); ok {
+		r0 = rf(ctx, method, endpoint, opts)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*retryablehttp.Request)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, string, interface{}) error); ok {
+		r1 = rf(ctx, method, endpoint, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ParseUrl provides a mock function with given fields: method, endpoint, opts
 func (_m *ShellyClient) ParseUrl(method string, endpoint string, opts interface{}) (string, error) {
 	ret := _m.Called(method, endpoint, opts)
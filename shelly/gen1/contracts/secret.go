@@ -0,0 +1,43 @@
+package contracts
+
+import (
+	"log/slog"
+	"net/url"
+)
+
+const redactedSecret = "[REDACTED]"
+
+// Secret holds a credential such as a password or a Wi-Fi key. It prints as
+// [REDACTED] through fmt and slog but keeps its value when encoded as JSON or
+// as query parameters, so it can be sent back to the device.
+type Secret string
+
+// String implements fmt.Stringer.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redactedSecret
+}
+
+// GoString implements fmt.GoStringer so %#v doesn't leak the value.
+func (s Secret) GoString() string {
+	return `"` + s.String() + `"`
+}
+
+// LogValue implements slog.LogValuer.
+func (s Secret) LogValue() slog.Value {
+	return slog.StringValue(s.String())
+}
+
+// EncodeValues implements query.Encoder so writes carry the real value.
+func (s Secret) EncodeValues(key string, v *url.Values) error {
+	v.Add(key, string(s))
+	return nil
+}
+
+// Reveal returns the plain value. A string conversion works too, Reveal is
+// the explicit and greppable way to get at the plaintext.
+func (s Secret) Reveal() string {
+	return string(s)
+}
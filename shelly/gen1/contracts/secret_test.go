@@ -0,0 +1,65 @@
+package contracts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/google/go-querystring/query"
+	"github.com/stretchr/testify/assert"
+)
+
+type secretHolder struct {
+	Name string `json:"name" url:"name"`
+	Key  Secret `json:"key" url:"key"`
+}
+
+func TestSecretFormatting(t *testing.T) {
+	type test struct {
+		title  string
+		format string
+		want   string
+	}
+	holder := secretHolder{Name: "ap", Key: "wifi-secret"}
+	tests := []test{
+		{title: "Default verb", format: "%v", want: "{ap [REDACTED]}"},
+		{title: "Plus verb", format: "%+v", want: "{Name:ap Key:[REDACTED]}"},
+		{title: "Go syntax verb", format: "%#v", want: `contracts.secretHolder{Name:"ap", Key:"[REDACTED]"}`},
+		{title: "String verb", format: "%s", want: "{ap [REDACTED]}"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.Equal(t, tc.want, fmt.Sprintf(tc.format, holder))
+		})
+	}
+}
+
+func TestSecretEmpty(t *testing.T) {
+	assert.Equal(t, "", Secret("").String())
+}
+
+func TestSecretLogValue(t *testing.T) {
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	logger.Info("settings", "key", Secret("wifi-secret"))
+	assert.Contains(t, buf.String(), "key=[REDACTED]")
+	assert.NotContains(t, buf.String(), "wifi-secret")
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	holder := secretHolder{Name: "ap", Key: "wifi-secret"}
+
+	body, err := json.Marshal(holder)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"name":"ap","key":"wifi-secret"}`, string(body))
+
+	var decoded secretHolder
+	assert.NoError(t, json.Unmarshal(body, &decoded))
+	assert.Equal(t, "wifi-secret", decoded.Key.Reveal())
+
+	values, err := query.Values(holder)
+	assert.NoError(t, err)
+	assert.Equal(t, "wifi-secret", values.Get("key"))
+}
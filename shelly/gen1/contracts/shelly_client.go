@@ -3,6 +3,8 @@ package contracts
 import (
 	"context"
 	"net/http"
+	"net/url"
+	"time"
 
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
 )
@@ -10,6 +12,7 @@ import (
 type ShellyClient interface {
 	RetryHTTPCheck(ctx context.Context, resp *http.Response, err error) (bool, error)
 	NewRequest(method, endpoint string, opts interface{}) (*retryablehttp.Request, error)
+	NewRequestWithContext(ctx context.Context, method, endpoint string, opts interface{}) (*retryablehttp.Request, error)
 	ParseUrl(method, endpoint string, opts interface{}) (string, error)
 	SetAdditionalHeaders(request *retryablehttp.Request, headers http.Header)
 	SetBasicAuth(request *retryablehttp.Request) error
@@ -18,4 +21,17 @@ type ShellyClient interface {
 
 type Response struct {
 	*http.Response
+	// Latency is the time spent on the request, retries included.
+	Latency time.Duration
+	// Attempts is the number of times the request was sent to the device.
+	Attempts int
+	// FinalURL is the url that produced the response, after redirects.
+	FinalURL *url.URL
+	// RawBody holds the response body when the client is set to keep it.
+	RawBody []byte
+	// DeviceTime is the device clock taken from the unixtime field, it's the
+	// zero time when the response doesn't carry it.
+	DeviceTime time.Time
+	// Cached is set when the response was served from the client cache.
+	Cached bool
 }
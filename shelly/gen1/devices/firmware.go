@@ -0,0 +1,232 @@
+package devices
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	firmwareDateLayout = "20060102-150405"
+	firmwareNotation   = "<yyyymmdd-hhmmss>/<branch or version>[@commit|-gcommit]"
+)
+
+var (
+	semverPattern  = regexp.MustCompile(`^v?(\d+)\.(\d+)(?:\.(\d+))?(?:-([0-9A-Za-z.]+?))?$`)
+	describeSuffix = regexp.MustCompile(`^(.*)-g([0-9a-f]{6,40})$`)
+)
+
+// SemVer is the semantic version of a release build.
+type SemVer struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease string
+}
+
+func (v SemVer) String() string {
+	s := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 when v is older, equal or newer than o.
+// Pre-releases are older than the release they precede.
+func (v SemVer) Compare(o SemVer) int {
+	for _, d := range []int{v.Major - o.Major, v.Minor - o.Minor, v.Patch - o.Patch} {
+		if d != 0 {
+			return sign(d)
+		}
+	}
+	switch {
+	case v.PreRelease == o.PreRelease:
+		return 0
+	case v.PreRelease == "":
+		return 1
+	case o.PreRelease == "":
+		return -1
+	}
+	return comparePreRelease(v.PreRelease, o.PreRelease)
+}
+
+// comparePreRelease follows the precedence rules of SemVer 2.0: dot
+// separated identifiers are compared one by one, numeric ones numerically
+// and below alphanumeric ones, and a shorter list is older when all of its
+// identifiers are equal.
+func comparePreRelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if c := compareIdentifier(as[i], bs[i]); c != 0 {
+			return c
+		}
+	}
+	if len(as) == len(bs) {
+		return 0
+	}
+	return sign(len(as) - len(bs))
+}
+
+// compareIdentifier compares single pre-release identifiers. Shelly tags
+// like rc10 or beta2 are compared by name and then by number, so rc2 is
+// older than rc10.
+func compareIdentifier(a, b string) int {
+	an, aNumeric := parseNumeric(a)
+	bn, bNumeric := parseNumeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		if an == bn {
+			return 0
+		}
+		return sign(an - bn)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	}
+
+	aName, aNum := splitNumericSuffix(a)
+	bName, bNum := splitNumericSuffix(b)
+	if aName == bName && aNum != "" && bNum != "" {
+		an, _ := parseNumeric(aNum)
+		bn, _ := parseNumeric(bNum)
+		if an != bn {
+			return sign(an - bn)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+func parseNumeric(s string) (int, bool) {
+	if s == "" || strings.Trim(s, "0123456789") != "" {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+func splitNumericSuffix(s string) (name, num string) {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	return s[:i], s[i:]
+}
+
+func sign(d int) int {
+	if d < 0 {
+		return -1
+	}
+	return 1
+}
+
+// ParseSemVer parses versions like v1.14.0, 1.0.8 or v1.13.0-rc1. The patch
+// number is optional.
+func ParseSemVer(s string) (SemVer, error) {
+	m := semverPattern.FindStringSubmatch(s)
+	if m == nil {
+		return SemVer{}, fmt.Errorf("invalid version %q", s)
+	}
+	v := SemVer{PreRelease: m[4]}
+	v.Major, _ = strconv.Atoi(m[1])
+	v.Minor, _ = strconv.Atoi(m[2])
+	if m[3] != "" {
+		v.Patch, _ = strconv.Atoi(m[3])
+	}
+	return v, nil
+}
+
+// FirmwareVersion is a parsed firmware string like
+// 20161223-111304/master@2bc16496 or 20230913-112003/v1.14.0-gcb84623.
+// Release builds carry a semantic version, development builds a branch.
+type FirmwareVersion struct {
+	Raw       string
+	BuildDate time.Time
+	Branch    string
+	Version   *SemVer
+	Commit    string
+}
+
+// ParseFirmwareVersion parses the fw fields of /shelly, /settings and /ota.
+func ParseFirmwareVersion(s string) (FirmwareVersion, error) {
+	date, build, ok := strings.Cut(strings.TrimSpace(s), "/")
+	if !ok {
+		return FirmwareVersion{}, fmt.Errorf("invalid firmware %q, expected %s", s, firmwareNotation)
+	}
+	buildDate, err := time.Parse(firmwareDateLayout, date)
+	if err != nil {
+		return FirmwareVersion{}, fmt.Errorf("invalid firmware build date %q: %w", date, err)
+	}
+
+	fw := FirmwareVersion{Raw: s, BuildDate: buildDate}
+	if name, commit, ok := strings.Cut(build, "@"); ok {
+		build, fw.Commit = name, commit
+	} else if m := describeSuffix.FindStringSubmatch(build); m != nil {
+		build, fw.Commit = m[1], m[2]
+	}
+	if build == "" {
+		return FirmwareVersion{}, fmt.Errorf("invalid firmware %q, expected %s", s, firmwareNotation)
+	}
+	if v, err := ParseSemVer(build); err == nil {
+		fw.Version = &v
+	} else {
+		fw.Branch = build
+	}
+	return fw, nil
+}
+
+func (f FirmwareVersion) String() string {
+	return f.Raw
+}
+
+// Compare returns -1, 0 or 1 when f is older, equal or newer than o. Semantic
+// versions are compared when both builds have one, build dates otherwise.
+func (f FirmwareVersion) Compare(o FirmwareVersion) int {
+	if f.Version != nil && o.Version != nil {
+		if c := f.Version.Compare(*o.Version); c != 0 {
+			return c
+		}
+	}
+	return f.BuildDate.Compare(o.BuildDate)
+}
+
+// AtLeast tells if the firmware is a release build of version v or newer,
+// v being something like v1.14 or 1.9.4. Development builds without a
+// version never match.
+func (f FirmwareVersion) AtLeast(v string) (bool, error) {
+	target, err := ParseSemVer(v)
+	if err != nil {
+		return false, err
+	}
+	if f.Version == nil {
+		return false, nil
+	}
+	return f.Version.Compare(target) >= 0, nil
+}
+
+// FirmwareVersion parses the fw field of the response.
+func (r BaseShellyResponse) FirmwareVersion() (FirmwareVersion, error) {
+	return ParseFirmwareVersion(r.Fw)
+}
+
+// Versions parses the old, new and beta versions of the response. Beta is
+// nil when the device reports no beta version.
+func (r BaseOtaResponse) Versions() (current, latest FirmwareVersion, beta *FirmwareVersion, err error) {
+	if current, err = ParseFirmwareVersion(r.OldVersion); err != nil {
+		return
+	}
+	if latest, err = ParseFirmwareVersion(r.NewVersion); err != nil {
+		return
+	}
+	if r.BetaVersion != "" {
+		var b FirmwareVersion
+		if b, err = ParseFirmwareVersion(r.BetaVersion); err != nil {
+			return
+		}
+		beta = &b
+	}
+	return
+}
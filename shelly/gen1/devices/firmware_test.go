@@ -0,0 +1,187 @@
+package devices
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFirmwareVersion(t *testing.T) {
+	type test struct {
+		title     string
+		input     string
+		want      FirmwareVersion
+		wantError bool
+	}
+	tests := []test{
+		{
+			title: "Development build",
+			input: "20161223-111304/master@2bc16496",
+			want: FirmwareVersion{
+				Raw:       "20161223-111304/master@2bc16496",
+				BuildDate: time.Date(2016, 12, 23, 11, 13, 4, 0, time.UTC),
+				Branch:    "master",
+				Commit:    "2bc16496",
+			},
+		},
+		{
+			title: "Release build with git describe commit",
+			input: "20230913-112003/v1.14.0-gcb84623",
+			want: FirmwareVersion{
+				Raw:       "20230913-112003/v1.14.0-gcb84623",
+				BuildDate: time.Date(2023, 9, 13, 11, 20, 3, 0, time.UTC),
+				Version:   &SemVer{Major: 1, Minor: 14},
+				Commit:    "cb84623",
+			},
+		},
+		{
+			title: "Release candidate",
+			input: "20230503-101129/v1.13.0-rc1@3f0b2d43",
+			want: FirmwareVersion{
+				Raw:       "20230503-101129/v1.13.0-rc1@3f0b2d43",
+				BuildDate: time.Date(2023, 5, 3, 10, 11, 29, 0, time.UTC),
+				Version:   &SemVer{Major: 1, Minor: 13, PreRelease: "rc1"},
+				Commit:    "3f0b2d43",
+			},
+		},
+		{
+			title: "Gen2 build without v prefix",
+			input: "20231107-164738/1.0.8-g8c7bb8d",
+			want: FirmwareVersion{
+				Raw:       "20231107-164738/1.0.8-g8c7bb8d",
+				BuildDate: time.Date(2023, 11, 7, 16, 47, 38, 0, time.UTC),
+				Version:   &SemVer{Major: 1, Minor: 0, Patch: 8},
+				Commit:    "8c7bb8d",
+			},
+		},
+		{title: "Missing build", input: "20161223-111304", wantError: true},
+		{title: "Invalid date", input: "2016-12-23/master@2bc16496", wantError: true},
+		{title: "Empty build name", input: "20161223-111304/@2bc16496", wantError: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			fw, err := ParseFirmwareVersion(tc.input)
+			if tc.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, fw)
+				assert.Equal(t, tc.input, fw.String())
+			}
+		})
+	}
+}
+
+func TestSemVerCompare(t *testing.T) {
+	type test struct {
+		title string
+		a     string
+		b     string
+		want  int
+	}
+	tests := []test{
+		{title: "Numeric identifiers numerically", a: "1.0.0-beta.11", b: "1.0.0-beta.2", want: 1},
+		{title: "Numeric below alphanumeric", a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", want: -1},
+		{title: "Shorter list is older", a: "1.0.0-alpha", b: "1.0.0-alpha.1", want: -1},
+		{title: "Alphanumeric identifiers lexically", a: "1.0.0-beta", b: "1.0.0-alpha.beta", want: 1},
+		{title: "Shelly rc tags numerically", a: "1.0.0-rc2", b: "1.0.0-rc10", want: -1},
+		{title: "Shelly beta tags numerically", a: "1.0.0-beta10", b: "1.0.0-beta9", want: 1},
+		{title: "Shelly beta before rc", a: "1.0.0-beta10", b: "1.0.0-rc1", want: -1},
+		{title: "Equal pre-releases", a: "1.0.0-rc.1", b: "1.0.0-rc.1", want: 0},
+		{title: "Pre-release before release", a: "1.0.0-rc.1", b: "1.0.0", want: -1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			a, err := ParseSemVer(tc.a)
+			assert.NoError(t, err)
+			b, err := ParseSemVer(tc.b)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, a.Compare(b))
+			assert.Equal(t, -tc.want, b.Compare(a))
+		})
+	}
+}
+
+func TestFirmwareVersionCompare(t *testing.T) {
+	type test struct {
+		title string
+		a     string
+		b     string
+		want  int
+	}
+	tests := []test{
+		{title: "Newer release", a: "20230913-112003/v1.14.0-gcb84623", b: "20210115-103659/v1.9.4@e2732e05", want: 1},
+		{title: "Older release", a: "20210115-103659/v1.9.4@e2732e05", b: "20230913-112003/v1.14.0-gcb84623", want: -1},
+		{title: "Release candidate before release", a: "20230503-101129/v1.13.0-rc1@3f0b2d43", b: "20230601-101129/v1.13.0@3f0b2d43", want: -1},
+		{title: "Release candidates by number", a: "20230601-101129/v1.13.0-rc10@3f0b2d43", b: "20230503-101129/v1.13.0-rc2@3f0b2d43", want: 1},
+		{title: "Beta before release candidate", a: "20230601-101129/v1.13.0-beta3@3f0b2d43", b: "20230503-101129/v1.13.0-rc1@3f0b2d43", want: -1},
+		{title: "Same version", a: "20230913-112003/v1.14.0-gcb84623", b: "20230913-112003/v1.14.0-gcb84623", want: 0},
+		{title: "Development builds by date", a: "20161223-111304/master@2bc16496", b: "20170101-111304/master@aaaaaaaa", want: -1},
+		{title: "Mixed builds by date", a: "20230913-112003/v1.14.0-gcb84623", b: "20161223-111304/master@2bc16496", want: 1},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			a, err := ParseFirmwareVersion(tc.a)
+			assert.NoError(t, err)
+			b, err := ParseFirmwareVersion(tc.b)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, a.Compare(b))
+		})
+	}
+}
+
+func TestFirmwareVersionAtLeast(t *testing.T) {
+	type test struct {
+		title     string
+		firmware  string
+		version   string
+		want      bool
+		wantError bool
+	}
+	tests := []test{
+		{title: "Same minor", firmware: "20230913-112003/v1.14.0-gcb84623", version: "v1.14", want: true},
+		{title: "Newer minor", firmware: "20230913-112003/v1.14.0-gcb84623", version: "1.9.4", want: true},
+		{title: "Older minor", firmware: "20210115-103659/v1.9.4@e2732e05", version: "v1.10", want: false},
+		{title: "Development build", firmware: "20161223-111304/master@2bc16496", version: "v1.0", want: false},
+		{title: "Invalid target", firmware: "20161223-111304/master@2bc16496", version: "latest", wantError: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			fw, err := ParseFirmwareVersion(tc.firmware)
+			assert.NoError(t, err)
+			got, err := fw.AtLeast(tc.version)
+			if tc.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestResponseFirmwareVersions(t *testing.T) {
+	shelly := BaseShellyResponse{Fw: "20161223-111304/master@2bc16496"}
+	fw, err := shelly.FirmwareVersion()
+	assert.NoError(t, err)
+	assert.Equal(t, "master", fw.Branch)
+
+	ota := BaseOtaResponse{
+		OldVersion: "20210115-103659/v1.9.4@e2732e05",
+		NewVersion: "20230913-112003/v1.14.0-gcb84623",
+	}
+	current, latest, beta, err := ota.Versions()
+	assert.NoError(t, err)
+	assert.Nil(t, beta)
+	assert.Equal(t, -1, current.Compare(latest))
+
+	ota.BetaVersion = "20230503-101129/v1.13.0-rc1@3f0b2d43"
+	_, _, beta, err = ota.Versions()
+	assert.NoError(t, err)
+	assert.Equal(t, "rc1", beta.Version.PreRelease)
+
+	ota.NewVersion = "2"
+	_, _, _, err = ota.Versions()
+	assert.Error(t, err)
+}
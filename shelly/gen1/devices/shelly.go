@@ -1,11 +1,14 @@
 package devices
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"time"
 
+	"github.com/rubemlrm/go-shelly/shelly/gen1/coiot"
 	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+	"github.com/rubemlrm/go-shelly/shelly/models"
 )
 
 type BaseShellyResponse struct {
@@ -53,9 +56,9 @@ type BaseDevice struct {
 	Hostname string `json:"hostname,omitempty"`
 }
 type BaseWifiAp struct {
-	Enabled bool   `json:"enabled,omitempty"`
-	Ssid    string `json:"ssid,omitempty"`
-	Key     string `json:"key,omitempty"`
+	Enabled bool             `json:"enabled,omitempty"`
+	Ssid    string           `json:"ssid,omitempty"`
+	Key     contracts.Secret `json:"key,omitempty"`
 }
 type BaseWifiSta struct {
 	Enabled    bool   `json:"enabled,omitempty"`
@@ -75,17 +78,17 @@ type (
 )
 
 type BaseMqtt struct {
-	Enable              bool    `json:"enable,omitempty"`
-	Server              string  `json:"server,omitempty"`
-	User                string  `json:"user,omitempty"`
-	ID                  string  `json:"id,omitempty"`
-	ReconnectTimeoutMax float32 `json:"reconnect_timeout_max,omitempty"`
-	ReconnectTimeoutMin float32 `json:"reconnect_timeout_min,omitempty"`
-	CleanSession        bool    `json:"clean_session,omitempty"`
-	KeepAlive           int     `json:"keep_alive,omitempty"`
-	MaxQos              int     `json:"max_qos,omitempty"`
-	Retain              bool    `json:"retain,omitempty"`
-	UpdatePeriod        int     `json:"update_period,omitempty"`
+	Enable              bool             `json:"enable,omitempty"`
+	Server              string           `json:"server,omitempty"`
+	User                contracts.Secret `json:"user,omitempty"`
+	ID                  string           `json:"id,omitempty"`
+	ReconnectTimeoutMax float32          `json:"reconnect_timeout_max,omitempty"`
+	ReconnectTimeoutMin float32          `json:"reconnect_timeout_min,omitempty"`
+	CleanSession        bool             `json:"clean_session,omitempty"`
+	KeepAlive           int              `json:"keep_alive,omitempty"`
+	MaxQos              int              `json:"max_qos,omitempty"`
+	Retain              bool             `json:"retain,omitempty"`
+	UpdatePeriod        int              `json:"update_period,omitempty"`
 }
 type BaseCoiot struct {
 	Enabled      bool   `json:"enabled,omitempty"`
@@ -97,10 +100,10 @@ type BaseSntp struct {
 	Enabled bool   `json:"enabled,omitempty"`
 }
 type BaseLogin struct {
-	Enabled     bool   `json:"enabled,omitempty"`
-	Unprotected bool   `json:"unprotected,omitempty"`
-	Username    string `json:"username,omitempty"`
-	Password    string `json:"-"`
+	Enabled     bool             `json:"enabled,omitempty"`
+	Unprotected bool             `json:"unprotected,omitempty"`
+	Username    string           `json:"username,omitempty"`
+	Password    contracts.Secret `json:"password,omitempty"`
 }
 type BaseBuildInfo struct {
 	BuildID        string    `json:"build_id,omitempty"`
@@ -144,6 +147,9 @@ type BaseWifiScanResults struct {
 
 type ShellyService struct {
 	Client contracts.ShellyClient
+	// Model makes the service refuse endpoints the device doesn't expose
+	// before sending any request, it's optional.
+	Model *models.Model
 }
 
 func NewShellyService(client contracts.ShellyClient) *ShellyService {
@@ -153,70 +159,82 @@ func NewShellyService(client contracts.ShellyClient) *ShellyService {
 }
 
 func (s *ShellyService) GetShelly() (*BaseShellyResponse, *contracts.Response, error) {
-	req, err := s.Client.NewRequest(http.MethodGet, "/shelly", nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	var info BaseShellyResponse
-	resp, err := s.Client.Do(req, &info)
-	if err != nil {
-		return nil, resp, err
-	}
-	return &info, resp, nil
+	return s.GetShellyWithContext(context.Background())
+}
+
+func (s *ShellyService) GetShellyWithContext(ctx context.Context) (*BaseShellyResponse, *contracts.Response, error) {
+	return get[BaseShellyResponse](ctx, s, "/shelly")
 }
 
 func (s *ShellyService) GetSettings() (*BaseSettingsResponse, *contracts.Response, error) {
-	req, err := s.Client.NewRequest(http.MethodGet, "/settings", nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	var info BaseSettingsResponse
-	resp, err := s.Client.Do(req, &info)
-	if err != nil {
-		return nil, resp, err
-	}
-	fmt.Print(resp)
-	return &info, resp, nil
+	return s.GetSettingsWithContext(context.Background())
+}
+
+func (s *ShellyService) GetSettingsWithContext(ctx context.Context) (*BaseSettingsResponse, *contracts.Response, error) {
+	return get[BaseSettingsResponse](ctx, s, "/settings")
 }
 
 func (s *ShellyService) GetOta() (*BaseOtaResponse, *contracts.Response, error) {
-	req, err := s.Client.NewRequest(http.MethodGet, "/ota", nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	var info BaseOtaResponse
-	resp, err := s.Client.Do(req, &info)
-	if err != nil {
-		return nil, resp, err
-	}
-	fmt.Print(resp)
-	return &info, resp, nil
+	return s.GetOtaWithContext(context.Background())
+}
+
+func (s *ShellyService) GetOtaWithContext(ctx context.Context) (*BaseOtaResponse, *contracts.Response, error) {
+	return get[BaseOtaResponse](ctx, s, "/ota")
 }
 
 func (s *ShellyService) GetOtaCheck() (*BaseOtaCheck, *contracts.Response, error) {
-	req, err := s.Client.NewRequest(http.MethodGet, "/ota/check", nil)
-	if err != nil {
-		return nil, nil, err
-	}
-	var info BaseOtaCheck
-	resp, err := s.Client.Do(req, &info)
-	if err != nil {
-		return nil, resp, err
-	}
-	fmt.Print(resp)
-	return &info, resp, nil
+	return s.GetOtaCheckWithContext(context.Background())
+}
+
+func (s *ShellyService) GetOtaCheckWithContext(ctx context.Context) (*BaseOtaCheck, *contracts.Response, error) {
+	return get[BaseOtaCheck](ctx, s, "/ota/check")
 }
 
 func (s *ShellyService) GetWifiScan() (*BaseWifiScan, *contracts.Response, error) {
-	req, err := s.Client.NewRequest(http.MethodGet, "/wifiscan", nil)
+	return s.GetWifiScanWithContext(context.Background())
+}
+
+func (s *ShellyService) GetWifiScanWithContext(ctx context.Context) (*BaseWifiScan, *contracts.Response, error) {
+	return get[BaseWifiScan](ctx, s, "/wifiscan")
+}
+
+func (s *ShellyService) GetCoiotDescription() (*coiot.Description, *contracts.Response, error) {
+	return s.GetCoiotDescriptionWithContext(context.Background())
+}
+
+func (s *ShellyService) GetCoiotDescriptionWithContext(ctx context.Context) (*coiot.Description, *contracts.Response, error) {
+	return get[coiot.Description](ctx, s, "/cit/d")
+}
+
+func (s *ShellyService) GetCoiotStatus() (*coiot.Status, *contracts.Response, error) {
+	return s.GetCoiotStatusWithContext(context.Background())
+}
+
+func (s *ShellyService) GetCoiotStatusWithContext(ctx context.Context) (*coiot.Status, *contracts.Response, error) {
+	return get[coiot.Status](ctx, s, "/cit/s")
+}
+
+// get sends a GET to endpoint with ctx, which carries the deadline and the
+// limiter priority, see transport.WithPriority.
+func get[T any](ctx context.Context, s *ShellyService, endpoint string) (*T, *contracts.Response, error) {
+	if err := s.supports(endpoint); err != nil {
+		return nil, nil, err
+	}
+	req, err := s.Client.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return nil, nil, err
 	}
-	var info BaseWifiScan
+	var info T
 	resp, err := s.Client.Do(req, &info)
 	if err != nil {
 		return nil, resp, err
 	}
-	fmt.Print(resp)
 	return &info, resp, nil
 }
+
+func (s *ShellyService) supports(endpoint string) error {
+	if s.Model == nil || s.Model.Supports(endpoint) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s on %s", models.ErrUnsupportedEndpoint, endpoint, s.Model.Code)
+}
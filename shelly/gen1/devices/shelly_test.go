@@ -1,14 +1,19 @@
 package devices
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/rubemlrm/go-shelly/shelly/gen1/coiot"
 	"github.com/rubemlrm/go-shelly/shelly/gen1/contracts/mocks"
 	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/rubemlrm/go-shelly/shelly/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -98,7 +103,7 @@ func TestGetShelly(t *testing.T) {
 
 func TestNewRequestFailure(t *testing.T) {
 	client := mocks.NewShellyClient(t)
-	client.On("NewRequest", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
+	client.On("NewRequestWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
 	cl := NewShellyService(client)
 	_, _, err := cl.GetShelly()
 	assert.Error(t, err)
@@ -158,7 +163,7 @@ func TestGetSettings(t *testing.T) {
 
 func TestGetSettingsNewRequestFailure(t *testing.T) {
 	client := mocks.NewShellyClient(t)
-	client.On("NewRequest", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
+	client.On("NewRequestWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
 	cl := NewShellyService(client)
 	_, _, err := cl.GetSettings()
 	assert.Error(t, err)
@@ -221,7 +226,7 @@ func TestGetOta(t *testing.T) {
 
 func TestGetOtaNewRequestFailure(t *testing.T) {
 	client := mocks.NewShellyClient(t)
-	client.On("NewRequest", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
+	client.On("NewRequestWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
 	cl := NewShellyService(client)
 	_, _, err := cl.GetOta()
 	assert.Error(t, err)
@@ -280,7 +285,7 @@ func TestGetOtaCheck(t *testing.T) {
 
 func TestGetOtaCheckNewRequestFailure(t *testing.T) {
 	client := mocks.NewShellyClient(t)
-	client.On("NewRequest", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
+	client.On("NewRequestWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
 	cl := NewShellyService(client)
 	_, _, err := cl.GetOtaCheck()
 	assert.Error(t, err)
@@ -340,12 +345,198 @@ func TestGetWifiScan(t *testing.T) {
 
 func TesGetWifiScanCheckNewRequestFailure(t *testing.T) {
 	client := mocks.NewShellyClient(t)
-	client.On("NewRequest", mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
+	client.On("NewRequestWithContext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, fmt.Errorf("testing"))
 	cl := NewShellyService(client)
 	_, _, err := cl.GetWifiScan()
 	assert.Error(t, err)
 }
 
+func TestGetCoiotDescription(t *testing.T) {
+	type test struct {
+		title     string
+		want      *coiot.Description
+		wantError bool
+		fixture   string
+	}
+
+	tests := []test{
+		{
+			title: "Testing Shelly response without error",
+			want: &coiot.Description{
+				Blocks: []coiot.Block{{ID: 1, Name: "relay_0"}, {ID: 2, Name: "device"}},
+				Sensors: []coiot.SensorDescription{
+					{ID: 9103, Type: "EVC", Name: "cfgChanged", Range: coiot.StringList{"U16"}, Links: coiot.IntList{2}},
+					{ID: 1101, Type: "S", Name: "output", Range: coiot.StringList{"0/1"}, Links: coiot.IntList{1}},
+					{ID: 4101, Type: "P", Name: "power", Unit: "W", Range: coiot.StringList{"0/2300", "-1"}, Links: coiot.IntList{1}},
+				},
+			},
+			wantError: false,
+			fixture:   "get_cit_d.json",
+		},
+		{
+			title:     "Testing Shelly response with error",
+			wantError: true,
+			fixture:   "get_cit_d_error.json",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			mux, client := SetupRestClient(t)
+			cl := NewShellyService(client)
+			mux.HandleFunc("/cit/d", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				fmt.Fprint(w, fixture(tc.fixture))
+			})
+			resp, _, err := cl.GetCoiotDescription()
+			if tc.wantError {
+				assert.Error(t, err)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, resp)
+			}
+		})
+	}
+}
+
+func TestGetCoiotStatus(t *testing.T) {
+	type test struct {
+		title     string
+		want      *coiot.Status
+		wantError bool
+		fixture   string
+	}
+
+	tests := []test{
+		{
+			title: "Testing Shelly response without error",
+			want: &coiot.Status{
+				Sensors: []coiot.Sensor{
+					{Channel: 0, ID: 9103, Value: float64(3)},
+					{Channel: 0, ID: 1101, Value: float64(1)},
+					{Channel: 0, ID: 4101, Value: 12.5},
+				},
+			},
+			wantError: false,
+			fixture:   "get_cit_s.json",
+		},
+		{
+			title:     "Testing Shelly response with error",
+			wantError: true,
+			fixture:   "get_cit_s_error.json",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			mux, client := SetupRestClient(t)
+			cl := NewShellyService(client)
+			mux.HandleFunc("/cit/s", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "GET", r.Method)
+				fmt.Fprint(w, fixture(tc.fixture))
+			})
+			resp, _, err := cl.GetCoiotStatus()
+			if tc.wantError {
+				assert.Error(t, err)
+				assert.Nil(t, resp)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, resp)
+			}
+		})
+	}
+}
+
+func TestUnsupportedEndpoint(t *testing.T) {
+	type test struct {
+		title    string
+		code     string
+		endpoint string
+		wantErr  bool
+	}
+	tests := []test{
+		{title: "Relay on relay model", code: "SHSW-1", endpoint: "/relay/0"},
+		{title: "Relay out of range", code: "SHSW-1", endpoint: "/relay/1", wantErr: true},
+		{title: "Relay on sensor", code: "SHHT-1", endpoint: "/relay/0", wantErr: true},
+		{title: "Roller on plug", code: "SHPLG2-1", endpoint: "/settings/roller/0", wantErr: true},
+		{title: "Model specific endpoint", code: "SHSEN-1", endpoint: "/ir/emit"},
+		{title: "Model specific endpoint on other model", code: "SHDIMW-1", endpoint: "/ir/emit", wantErr: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			m, ok := models.Lookup(tc.code)
+			assert.True(t, ok)
+			cl := NewShellyService(mocks.NewShellyClient(t))
+			cl.Model = &m
+			err := cl.supports(tc.endpoint)
+			if tc.wantErr {
+				assert.ErrorIs(t, err, models.ErrUnsupportedEndpoint)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestServicePriority(t *testing.T) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	client, err := transport.NewRestClient(transport.ClientOptions{
+		Hostname: server.URL,
+		Limiter:  &transport.LimiterOptions{MaxInFlight: 1},
+	})
+	assert.NoError(t, err)
+	cl := NewShellyService(client)
+
+	unblock := make(chan struct{})
+	order := make(chan string, 2)
+	mux.HandleFunc("/ota", func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+		fmt.Fprint(w, `{}`)
+	})
+	for _, endpoint := range []string{"/settings", "/shelly"} {
+		endpoint := endpoint
+		mux.HandleFunc(endpoint, func(w http.ResponseWriter, r *http.Request) {
+			order <- endpoint
+			fmt.Fprint(w, `{}`)
+		})
+	}
+
+	var wg sync.WaitGroup
+	run := func(f func()) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f()
+		}()
+		// give the request time to take the slot or queue for it
+		time.Sleep(50 * time.Millisecond)
+	}
+	run(func() {
+		_, _, err := cl.GetOtaWithContext(context.Background())
+		assert.NoError(t, err)
+	})
+	run(func() {
+		_, _, err := cl.GetSettingsWithContext(transport.WithPriority(context.Background(), transport.PriorityBackground))
+		assert.NoError(t, err)
+	})
+	run(func() {
+		_, _, err := cl.GetShellyWithContext(transport.WithPriority(context.Background(), transport.PriorityControl))
+		assert.NoError(t, err)
+	})
+	close(unblock)
+	wg.Wait()
+	close(order)
+
+	var got []string
+	for endpoint := range order {
+		got = append(got, endpoint)
+	}
+	assert.Equal(t, []string{"/shelly", "/settings"}, got)
+}
+
 func fixture(path string) string {
 	b, err := os.ReadFile("./testdata/" + path)
 	if err != nil {
@@ -0,0 +1,154 @@
+package transport
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrEmptyAddress       = errors.New("address is empty")
+	ErrMalformedAddress   = errors.New("address is malformed")
+	ErrUnsupportedScheme  = errors.New("scheme must be http or https")
+	ErrInvalidHost        = errors.New("host must be an ip address or a hostname")
+	ErrInvalidPort        = errors.New("port must be a number between 1 and 65535")
+	ErrUnexpectedUserInfo = errors.New("credentials can't be part of the address")
+	ErrUnexpectedQuery    = errors.New("query and fragment can't be part of the address")
+)
+
+// AddressError is returned by ParseAddress, it wraps one of the Err*Address
+// errors above.
+type AddressError struct {
+	Address string
+	Err     error
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("invalid device address %q: %v", e.Address, e.Err)
+}
+
+func (e *AddressError) Unwrap() error {
+	return e.Err
+}
+
+// Address is a validated device address.
+type Address struct {
+	Scheme string
+	Host   string
+	Port   string
+	Path   string
+}
+
+// ParseAddress accepts bare IPv4 and IPv6 addresses, host:port pairs, mDNS
+// names like shelly1-ABC123.local and full urls. The scheme defaults to http
+// and trailing slashes are dropped from the path.
+func ParseAddress(address string) (Address, error) {
+	fail := func(err error) (Address, error) {
+		return Address{}, &AddressError{Address: address, Err: err}
+	}
+
+	raw := strings.TrimSpace(address)
+	if raw == "" {
+		return fail(ErrEmptyAddress)
+	}
+	if !strings.Contains(raw, "://") {
+		// bare IPv6 literals need brackets to be parsed as a url host
+		if ip := net.ParseIP(strings.SplitN(raw, "%", 2)[0]); ip != nil && strings.Contains(raw, ":") {
+			raw = "[" + raw + "]"
+		}
+		raw = "http://" + raw
+	}
+
+	u, err := url.Parse(escapeZone(raw))
+	if err != nil {
+		return fail(ErrMalformedAddress)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return fail(ErrUnsupportedScheme)
+	}
+	if u.User != nil {
+		return fail(ErrUnexpectedUserInfo)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.ForceQuery {
+		return fail(ErrUnexpectedQuery)
+	}
+	host := u.Hostname()
+	if !validHost(host) {
+		return fail(ErrInvalidHost)
+	}
+	port := u.Port()
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return fail(ErrInvalidPort)
+		}
+	} else if strings.HasSuffix(u.Host, ":") {
+		return fail(ErrInvalidPort)
+	}
+
+	return Address{
+		Scheme: scheme,
+		Host:   host,
+		Port:   port,
+		Path:   strings.TrimRight(u.Path, "/"),
+	}, nil
+}
+
+// escapeZone escapes the % of an IPv6 zone, like fe80::1%eth0, as %25 the
+// way url.Parse expects it. Zones that are already escaped are kept.
+func escapeZone(raw string) string {
+	start := strings.Index(raw, "[")
+	end := strings.Index(raw, "]")
+	if start < 0 || end < start {
+		return raw
+	}
+	host := raw[start:end]
+	i := strings.Index(host, "%")
+	if i < 0 || strings.HasPrefix(host[i:], "%25") {
+		return raw
+	}
+	return raw[:start+i] + "%25" + raw[start+i+1:]
+}
+
+func validHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	if net.ParseIP(strings.SplitN(host, "%", 2)[0]) != nil {
+		return true
+	}
+	if len(host) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// URL returns the address as the base url of a client.
+func (a Address) URL() *url.URL {
+	host := a.Host
+	if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	if a.Port != "" {
+		host += ":" + a.Port
+	}
+	return &url.URL{Scheme: a.Scheme, Host: host, Path: a.Path}
+}
+
+func (a Address) String() string {
+	return a.URL().String()
+}
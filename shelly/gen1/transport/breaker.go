@@ -0,0 +1,318 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrDeviceOffline is matched by the errors returned while the circuit of a
+// device is open.
+var ErrDeviceOffline = errors.New("device offline")
+
+// DeviceOfflineError is returned without contacting the device while its
+// circuit is open.
+type DeviceOfflineError struct {
+	Host  string
+	Since time.Time
+	// Err is the failure that opened the circuit.
+	Err error
+}
+
+func (e *DeviceOfflineError) Error() string {
+	return fmt.Sprintf("device %s offline since %s: %v", e.Host, e.Since.Format(time.RFC3339), e.Err)
+}
+
+func (e *DeviceOfflineError) Is(target error) bool {
+	return target == ErrDeviceOffline
+}
+
+func (e *DeviceOfflineError) Unwrap() error {
+	return e.Err
+}
+
+// BreakerState is the state of a device circuit.
+type BreakerState int
+
+const (
+	// BreakerClosed lets every request through.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails every request with a DeviceOfflineError.
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial request through to decide if the
+	// circuit should close again.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerOptions configures the circuit breaker of a device.
+type BreakerOptions struct {
+	// FailureThreshold is the number of consecutive failed requests that
+	// open the circuit. Defaults to 3.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before letting a trial
+	// request through. Defaults to 30 seconds.
+	OpenTimeout time.Duration
+	// ProbeInterval is how often /shelly is probed in the background while
+	// the circuit is open. Zero disables the probe.
+	ProbeInterval time.Duration
+	// ProbeTimeout bounds each probe request. Defaults to 2 seconds.
+	ProbeTimeout time.Duration
+	// OnStateChange is called on every state transition.
+	OnStateChange func(host string, from, to BreakerState)
+}
+
+type breaker struct {
+	opts     BreakerOptions
+	host     string
+	probe    func(ctx context.Context) error
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	lastErr  error
+	trial    bool
+	probing  bool
+	// probeStop stops the probe started by the last open.
+	probeStop chan struct{}
+	stop      chan struct{}
+}
+
+func newBreaker(host string, opts BreakerOptions, probe func(ctx context.Context) error) *breaker {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 30 * time.Second
+	}
+	if opts.ProbeTimeout <= 0 {
+		opts.ProbeTimeout = 2 * time.Second
+	}
+	return &breaker{
+		opts:  opts,
+		host:  host,
+		probe: probe,
+		stop:  make(chan struct{}),
+	}
+}
+
+// State returns the current state of the circuit.
+func (b *breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// allow returns a DeviceOfflineError when the request must not reach the
+// device, and whether the request is the half-open trial. The trial flag is
+// passed back to record or abort.
+func (b *breaker) allow() (bool, error) {
+	if b == nil {
+		return false, nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if time.Since(b.openedAt) < b.opts.OpenTimeout {
+			return false, b.offlineError()
+		}
+		b.setState(BreakerHalfOpen)
+		b.trial = true
+		return true, nil
+	case BreakerHalfOpen:
+		if b.trial {
+			return false, b.offlineError()
+		}
+		b.trial = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// record stores the outcome of a request that was allowed through. While
+// the circuit isn't closed only the trial changes its state, the outcome of
+// requests sent before it opened is stale.
+func (b *breaker) record(trial bool, err error) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case trial && b.state == BreakerHalfOpen:
+		b.trial = false
+		if err == nil {
+			b.closeCircuit()
+			return
+		}
+		b.lastErr = err
+		b.open()
+	case b.state == BreakerClosed:
+		if err == nil {
+			b.failures = 0
+			return
+		}
+		b.lastErr = err
+		b.failures++
+		if b.failures >= b.opts.FailureThreshold {
+			b.open()
+		}
+	}
+}
+
+// abort releases a trial request that ended without reaching the device.
+func (b *breaker) abort(trial bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if trial && b.state == BreakerHalfOpen {
+		b.trial = false
+	}
+}
+
+func (b *breaker) open() {
+	b.openedAt = time.Now()
+	b.setState(BreakerOpen)
+	if b.opts.ProbeInterval > 0 && b.probe != nil && !b.probing {
+		b.probing = true
+		b.probeStop = make(chan struct{})
+		go b.runProbe(b.probeStop)
+	}
+}
+
+// closeCircuit closes the circuit and stops the probe.
+func (b *breaker) closeCircuit() {
+	b.failures = 0
+	b.trial = false
+	if b.probing {
+		close(b.probeStop)
+		b.probing = false
+	}
+	b.setState(BreakerClosed)
+}
+
+func (b *breaker) setState(state BreakerState) {
+	if b.state == state {
+		return
+	}
+	from := b.state
+	b.state = state
+	if b.opts.OnStateChange != nil {
+		go b.opts.OnStateChange(b.host, from, state)
+	}
+}
+
+func (b *breaker) offlineError() error {
+	return &DeviceOfflineError{Host: b.host, Since: b.openedAt, Err: b.lastErr}
+}
+
+// runProbe polls the device until it answers or the circuit closes.
+func (b *breaker) runProbe(stop chan struct{}) {
+	ticker := time.NewTicker(b.opts.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), b.opts.ProbeTimeout)
+		err := b.probe(ctx)
+		cancel()
+		if err != nil {
+			continue
+		}
+
+		b.mu.Lock()
+		select {
+		case <-stop:
+			// the circuit closed while probing
+		default:
+			b.closeCircuit()
+		}
+		b.mu.Unlock()
+		return
+	}
+}
+
+func (b *breaker) close() {
+	if b == nil {
+		return
+	}
+	select {
+	case <-b.stop:
+	default:
+		close(b.stop)
+	}
+}
+
+// probeDevice checks if the device answers on /shelly, which never requires
+// authentication.
+func (c *Client) probeDevice(ctx context.Context) error {
+	u := *c.BaseURL
+	u.Path = c.BaseURL.Path + "/shelly"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.probeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("probe returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// BreakerState returns the state of the device circuit, it's always closed
+// when the breaker isn't enabled.
+func (c *Client) BreakerState() BreakerState {
+	if c.breaker == nil {
+		return BreakerClosed
+	}
+	return c.breaker.State()
+}
+
+// Close stops the background work of the client.
+func (c *Client) Close() {
+	c.breaker.close()
+}
+
+// recordOutcome feeds the breaker with the result of a request. Requests
+// canceled by the caller say nothing about the device, and neither do the
+// application errors accepted by WithApplicationErrors.
+func (c *Client) recordOutcome(ctx context.Context, trial bool, resp *http.Response, body []byte, err error) {
+	isApplicationError, _ := ctx.Value(applicationErrorKey{}).(func([]byte) bool)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		c.breaker.abort(trial)
+	case err != nil:
+		c.breaker.record(trial, err)
+	case resp.StatusCode >= http.StatusInternalServerError && (isApplicationError == nil || !isApplicationError(body)):
+		c.breaker.record(trial, fmt.Errorf("device returned status %d", resp.StatusCode))
+	default:
+		c.breaker.record(trial, nil)
+	}
+}
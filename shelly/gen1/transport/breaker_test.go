@@ -0,0 +1,211 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+type flakyDevice struct {
+	offline int32
+	calls   int32
+}
+
+func setupBreakerClient(t *testing.T, opts BreakerOptions) (*flakyDevice, *Client) {
+	device := &flakyDevice{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/shelly" {
+			atomic.AddInt32(&device.calls, 1)
+		}
+		if atomic.LoadInt32(&device.offline) == 1 {
+			conn, _, err := w.(http.Hijacker).Hijack()
+			assert.NoError(t, err)
+			conn.Close()
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	}))
+	t.Cleanup(server.Close)
+
+	c, err := NewRestClient(ClientOptions{Hostname: server.URL, Breaker: &opts})
+	assert.NoError(t, err)
+	t.Cleanup(c.Close)
+	return device, c
+}
+
+func doStatus(t *testing.T, c *Client) error {
+	req, err := c.NewRequest(http.MethodGet, "/status", nil)
+	assert.NoError(t, err)
+	_, err = c.Do(req, &struct{}{})
+	return err
+}
+
+func TestBreakerOpens(t *testing.T) {
+	device, c := setupBreakerClient(t, BreakerOptions{FailureThreshold: 2, OpenTimeout: time.Hour})
+	atomic.StoreInt32(&device.offline, 1)
+
+	assert.Error(t, doStatus(t, c))
+	assert.Equal(t, BreakerClosed, c.BreakerState())
+	assert.Error(t, doStatus(t, c))
+	assert.Equal(t, BreakerOpen, c.BreakerState())
+
+	err := doStatus(t, c)
+	assert.ErrorIs(t, err, ErrDeviceOffline)
+	var offline *DeviceOfflineError
+	assert.True(t, errors.As(err, &offline))
+	assert.NotEmpty(t, offline.Host)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&device.calls))
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	type test struct {
+		title     string
+		offline   int32
+		wantState BreakerState
+	}
+	tests := []test{
+		{
+			title:     "Successful trial closes the circuit",
+			offline:   0,
+			wantState: BreakerClosed,
+		},
+		{
+			title:     "Failed trial opens the circuit again",
+			offline:   1,
+			wantState: BreakerOpen,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			device, c := setupBreakerClient(t, BreakerOptions{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond})
+			atomic.StoreInt32(&device.offline, 1)
+			assert.Error(t, doStatus(t, c))
+			assert.Equal(t, BreakerOpen, c.BreakerState())
+
+			time.Sleep(20 * time.Millisecond)
+			atomic.StoreInt32(&device.offline, tc.offline)
+			_ = doStatus(t, c)
+			assert.Equal(t, tc.wantState, c.BreakerState())
+		})
+	}
+}
+
+func TestBreakerHalfOpenSingleTrial(t *testing.T) {
+	b := newBreaker("localhost", BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Millisecond}, nil)
+	b.record(false, errors.New("testing"))
+	time.Sleep(2 * time.Millisecond)
+
+	trial, err := b.allow()
+	assert.NoError(t, err)
+	assert.True(t, trial)
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrDeviceOffline)
+	b.abort(trial)
+	trial, err = b.allow()
+	assert.NoError(t, err)
+	assert.True(t, trial)
+}
+
+func TestBreakerIgnoresStaleOutcomes(t *testing.T) {
+	b := newBreaker("localhost", BreakerOptions{FailureThreshold: 1, OpenTimeout: 10 * time.Millisecond}, nil)
+	b.record(false, errors.New("testing"))
+	openedAt := b.openedAt
+
+	// a request sent before the circuit opened succeeds
+	b.record(false, nil)
+	assert.Equal(t, BreakerOpen, b.State())
+
+	time.Sleep(20 * time.Millisecond)
+	trial, err := b.allow()
+	assert.NoError(t, err)
+	assert.True(t, trial)
+
+	// a stale failure neither reopens the circuit nor ends the trial
+	b.record(false, errors.New("stale"))
+	assert.Equal(t, BreakerHalfOpen, b.State())
+	assert.Equal(t, openedAt, b.openedAt)
+	_, err = b.allow()
+	assert.ErrorIs(t, err, ErrDeviceOffline)
+
+	b.record(trial, nil)
+	assert.Equal(t, BreakerClosed, b.State())
+}
+
+func TestBreakerTrialStopsProbe(t *testing.T) {
+	ignore := goleak.IgnoreCurrent()
+	b := newBreaker("localhost", BreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Millisecond,
+		ProbeInterval:    time.Hour,
+	}, func(ctx context.Context) error { return nil })
+	defer b.close()
+	b.record(false, errors.New("testing"))
+	assert.True(t, b.probing)
+
+	time.Sleep(2 * time.Millisecond)
+	trial, err := b.allow()
+	assert.NoError(t, err)
+	b.record(trial, nil)
+	assert.Equal(t, BreakerClosed, b.State())
+	assert.False(t, b.probing)
+	goleak.VerifyNone(t, ignore)
+
+	// opening again starts a new probe
+	b.record(false, errors.New("testing"))
+	assert.True(t, b.probing)
+}
+
+func TestBreakerProbe(t *testing.T) {
+	changes := make(chan BreakerState, 4)
+	device, c := setupBreakerClient(t, BreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Hour,
+		ProbeInterval:    5 * time.Millisecond,
+		OnStateChange: func(host string, from, to BreakerState) {
+			changes <- to
+		},
+	})
+	atomic.StoreInt32(&device.offline, 1)
+	assert.Error(t, doStatus(t, c))
+	assert.Equal(t, BreakerOpen, <-changes)
+
+	atomic.StoreInt32(&device.offline, 0)
+	assert.Equal(t, BreakerClosed, <-changes)
+	assert.NoError(t, doStatus(t, c))
+}
+
+func TestBreakerProbeStopsOnClose(t *testing.T) {
+	changes := make(chan BreakerState, 4)
+	device, c := setupBreakerClient(t, BreakerOptions{
+		FailureThreshold: 1,
+		OpenTimeout:      time.Hour,
+		ProbeInterval:    5 * time.Millisecond,
+		OnStateChange: func(host string, from, to BreakerState) {
+			changes <- to
+		},
+	})
+	atomic.StoreInt32(&device.offline, 1)
+	ignore := goleak.IgnoreCurrent()
+	assert.Error(t, doStatus(t, c))
+	assert.Equal(t, BreakerOpen, <-changes)
+	assert.Error(t, goleak.Find(ignore), "the probe should run while the circuit is open")
+
+	c.Close()
+	goleak.VerifyNone(t, ignore)
+	assert.Equal(t, BreakerOpen, c.BreakerState())
+}
+
+func TestBreakerStateString(t *testing.T) {
+	assert.Equal(t, "closed", BreakerClosed.String())
+	assert.Equal(t, "open", BreakerOpen.String())
+	assert.Equal(t, "half-open", BreakerHalfOpen.String())
+}
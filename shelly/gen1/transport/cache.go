@@ -0,0 +1,180 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+)
+
+// CacheOptions enables the response cache for endpoints that change slowly,
+// like /shelly and /settings. Hits skip the limiter and the breaker but still
+// go through the client middlewares.
+type CacheOptions struct {
+	// TTL maps endpoint paths to how long their GET responses are kept.
+	// Endpoints that aren't listed are never cached.
+	TTL map[string]time.Duration
+}
+
+type cacheEntry struct {
+	status     int
+	header     http.Header
+	body       []byte
+	generation uint64
+	expires    time.Time
+}
+
+// responseCache holds the responses of a single device. Every successful
+// write bumps the cache generation, which works like a version shared by all
+// the entries: the ones stored under an older generation are stale. Gen1
+// devices don't send validators, so entries are never revalidated.
+type responseCache struct {
+	mu         sync.Mutex
+	ttl        map[string]time.Duration
+	generation uint64
+	entries    map[string]*cacheEntry
+}
+
+func newResponseCache(opts CacheOptions) *responseCache {
+	ttl := make(map[string]time.Duration, len(opts.TTL))
+	for endpoint, d := range opts.TTL {
+		ttl[normalizeEndpoint(endpoint)] = d
+	}
+	return &responseCache{
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+func normalizeEndpoint(endpoint string) string {
+	return "/" + strings.Trim(endpoint, "/")
+}
+
+// cacheKey returns the endpoint a request is cached under and whether it's
+// cacheable at all. Gen1 writes are GET requests with query parameters, so
+// only plain GET requests are cached.
+func (rc *responseCache) cacheKey(req *retryablehttp.Request, basePath string) (string, bool) {
+	if req.Request == nil || req.Method != http.MethodGet || req.URL.RawQuery != "" {
+		return "", false
+	}
+	key := normalizeEndpoint(strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(basePath, "/")))
+	_, ok := rc.ttl[key]
+	return key, ok
+}
+
+func (rc *responseCache) get(key string) (*cacheEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.generation != rc.generation || time.Now().After(entry.expires) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return entry, true
+}
+
+func (rc *responseCache) currentGeneration() uint64 {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.generation
+}
+
+// set stores a response fetched while the cache was at the given generation,
+// responses that raced with a write are dropped.
+func (rc *responseCache) set(key string, generation uint64, resp *http.Response, body []byte) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if generation != rc.generation {
+		return
+	}
+	rc.entries[key] = &cacheEntry{
+		status:     resp.StatusCode,
+		header:     resp.Header.Clone(),
+		body:       slices.Clone(body),
+		generation: generation,
+		expires:    time.Now().Add(rc.ttl[key]),
+	}
+}
+
+// invalidate drops the given endpoints, or every entry when none is given.
+func (rc *responseCache) invalidate(endpoints ...string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(endpoints) == 0 {
+		rc.generation++
+		rc.entries = make(map[string]*cacheEntry)
+		return
+	}
+	for _, endpoint := range endpoints {
+		delete(rc.entries, normalizeEndpoint(endpoint))
+	}
+}
+
+func (e *cacheEntry) response(req *retryablehttp.Request) *http.Response {
+	return &http.Response{
+		Status:     fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode: e.status,
+		Header:     e.header.Clone(),
+		Body:       io.NopCloser(bytes.NewReader(slices.Clone(e.body))),
+		Request:    req.Request,
+	}
+}
+
+// isWrite tells if a request changes the device state. Besides POST, PUT and
+// PATCH, Gen1 takes writes as GET requests with query parameters.
+func isWrite(req *retryablehttp.Request) bool {
+	if req.Request == nil {
+		return false
+	}
+	return req.Method != http.MethodGet || req.URL.RawQuery != ""
+}
+
+// InvalidateCache drops the cached responses for the given endpoints, or
+// the whole device cache when called without arguments.
+func (c *Client) InvalidateCache(endpoints ...string) {
+	if c.cache == nil {
+		return
+	}
+	c.cache.invalidate(endpoints...)
+}
+
+// cachedResponse serves a request from a cache entry, through the
+// middlewares so they see hits too.
+func (c *Client) cachedResponse(req *retryablehttp.Request, entry *cacheEntry, v interface{}) (*contracts.Response, error) {
+	resp, err := c.chain(func(req *retryablehttp.Request) (*http.Response, error) {
+		return entry.response(req), nil
+	})(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body != nil {
+		resp.Body.Close()
+	}
+	if len(entry.body) > 0 {
+		if err := json.Unmarshal(entry.body, v); err != nil {
+			return nil, err
+		}
+	}
+	response := &contracts.Response{
+		Response:   resp,
+		FinalURL:   req.URL,
+		DeviceTime: deviceTime(entry.body),
+		Cached:     true,
+	}
+	if c.keepBody {
+		// a copy, so callers can't change the cached body
+		response.RawBody = slices.Clone(entry.body)
+	}
+	return response, nil
+}
@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+type cacheServer struct {
+	settings int32
+	status   int32
+}
+
+func setupCacheClient(t *testing.T, opts CacheOptions) (*cacheServer, *Client) {
+	counters := &cacheServer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/settings", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&counters.settings, 1)
+		fmt.Fprintf(w, `{"name":"call-%d"}`, n)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&counters.status, 1)
+		fmt.Fprintf(w, `{"name":"call-%d"}`, n)
+	})
+	mux.HandleFunc("/relay/0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ison":true}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	c, err := NewRestClient(ClientOptions{Hostname: server.URL, Cache: &opts})
+	assert.NoError(t, err)
+	return counters, c
+}
+
+type cachedSettings struct {
+	Name string `json:"name"`
+}
+
+func getSettings(t *testing.T, c *Client, endpoint string) (string, bool) {
+	req, err := c.NewRequest(http.MethodGet, endpoint, nil)
+	assert.NoError(t, err)
+	var out cachedSettings
+	resp, err := c.Do(req, &out)
+	assert.NoError(t, err)
+	return out.Name, resp.Cached
+}
+
+func TestCacheHit(t *testing.T) {
+	counters, c := setupCacheClient(t, CacheOptions{TTL: map[string]time.Duration{"settings": time.Minute}})
+
+	name, cached := getSettings(t, c, "/settings")
+	assert.Equal(t, "call-1", name)
+	assert.False(t, cached)
+
+	name, cached = getSettings(t, c, "/settings")
+	assert.Equal(t, "call-1", name)
+	assert.True(t, cached)
+	assert.Equal(t, int32(1), counters.settings)
+
+	// endpoints without ttl are never cached
+	getSettings(t, c, "/status")
+	getSettings(t, c, "/status")
+	assert.Equal(t, int32(2), counters.status)
+}
+
+func TestCacheBodyIsCopied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"kitchen"}`)
+	}))
+	t.Cleanup(server.Close)
+	c, err := NewRestClient(ClientOptions{
+		Hostname: server.URL,
+		KeepBody: true,
+		Cache:    &CacheOptions{TTL: map[string]time.Duration{"settings": time.Minute}},
+	})
+	assert.NoError(t, err)
+
+	get := func() *contracts.Response {
+		req, err := c.NewRequest(http.MethodGet, "/settings", nil)
+		assert.NoError(t, err)
+		resp, err := c.Do(req, &cachedSettings{})
+		assert.NoError(t, err)
+		return resp
+	}
+	first := get()
+	assert.False(t, first.Cached)
+	copy(first.RawBody, "XXXX")
+
+	hit := get()
+	assert.True(t, hit.Cached)
+	assert.Equal(t, `{"name":"kitchen"}`, string(hit.RawBody))
+	assert.Equal(t, "200 OK", hit.Status)
+	copy(hit.RawBody, "XXXX")
+
+	hit = get()
+	assert.Equal(t, `{"name":"kitchen"}`, string(hit.RawBody))
+}
+
+func TestCacheExpiration(t *testing.T) {
+	counters, c := setupCacheClient(t, CacheOptions{TTL: map[string]time.Duration{"/settings": 10 * time.Millisecond}})
+
+	getSettings(t, c, "/settings")
+	time.Sleep(20 * time.Millisecond)
+	name, cached := getSettings(t, c, "/settings")
+	assert.Equal(t, "call-2", name)
+	assert.False(t, cached)
+	assert.Equal(t, int32(2), counters.settings)
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	type test struct {
+		title      string
+		invalidate func(t *testing.T, c *Client)
+	}
+	tests := []test{
+		{
+			title: "Gen1 write with query parameters",
+			invalidate: func(t *testing.T, c *Client) {
+				req, err := c.NewRequest(http.MethodGet, "/relay/0", map[string]string{"turn": "on"})
+				assert.NoError(t, err)
+				_, err = c.Do(req, &struct{}{})
+				assert.NoError(t, err)
+			},
+		},
+		{
+			title: "Form post",
+			invalidate: func(t *testing.T, c *Client) {
+				req, err := c.NewRequest(http.MethodPost, "/relay/0", Form(map[string]string{"turn": "on"}))
+				assert.NoError(t, err)
+				_, err = c.Do(req, &struct{}{})
+				assert.NoError(t, err)
+			},
+		},
+		{
+			title: "Explicit endpoint invalidation",
+			invalidate: func(t *testing.T, c *Client) {
+				c.InvalidateCache("/settings")
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			counters, c := setupCacheClient(t, CacheOptions{TTL: map[string]time.Duration{"/settings": time.Minute}})
+			getSettings(t, c, "/settings")
+			tc.invalidate(t, c)
+			name, cached := getSettings(t, c, "/settings")
+			assert.Equal(t, "call-2", name)
+			assert.False(t, cached)
+			assert.Equal(t, int32(2), counters.settings)
+		})
+	}
+}
+
+func TestCacheDropsResponsesRacingWrites(t *testing.T) {
+	rc := newResponseCache(CacheOptions{TTL: map[string]time.Duration{"/settings": time.Minute}})
+	generation := rc.currentGeneration()
+	rc.invalidate()
+	rc.set("/settings", generation, &http.Response{StatusCode: http.StatusOK}, []byte(`{}`))
+	_, ok := rc.get("/settings")
+	assert.False(t, ok)
+}
+
+func TestCacheHitRunsMiddlewares(t *testing.T) {
+	counters, c := setupCacheClient(t, CacheOptions{TTL: map[string]time.Duration{"/settings": time.Minute}})
+	var seen []int
+	c.Use(TimingMiddleware(func(_ *retryablehttp.Request, resp *http.Response, _ time.Duration, err error) {
+		assert.NoError(t, err)
+		seen = append(seen, resp.StatusCode)
+	}))
+
+	getSettings(t, c, "/settings")
+	name, cached := getSettings(t, c, "/settings")
+	assert.Equal(t, "call-1", name)
+	assert.True(t, cached)
+	assert.Equal(t, int32(1), counters.settings)
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, seen)
+}
@@ -0,0 +1,138 @@
+package transport
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+)
+
+// ErrCredentialsWithoutAuth is returned by NewRestClient, which never
+// authenticates, when ClientOptions.Credentials is set.
+var ErrCredentialsWithoutAuth = errors.New("credentials require an auth client")
+
+// Credentials holds the username and password of a device.
+type Credentials struct {
+	Username string
+	Password contracts.Secret
+}
+
+// DeviceIdentity identifies the device credentials are requested for.
+type DeviceIdentity struct {
+	Host string
+	MAC  string
+}
+
+// CredentialProvider returns the credentials of a device. It's called for
+// every request, so rotated credentials are picked up without rebuilding
+// the client.
+type CredentialProvider interface {
+	Credentials(ctx context.Context, device DeviceIdentity) (Credentials, error)
+}
+
+// StaticCredentials always returns the same credentials.
+type StaticCredentials Credentials
+
+func (s StaticCredentials) Credentials(_ context.Context, _ DeviceIdentity) (Credentials, error) {
+	return Credentials(s), nil
+}
+
+// EnvCredentials reads the credentials from environment variables.
+type EnvCredentials struct {
+	UsernameVar string
+	PasswordVar string
+}
+
+func (e EnvCredentials) Credentials(_ context.Context, _ DeviceIdentity) (Credentials, error) {
+	username, ok := os.LookupEnv(e.UsernameVar)
+	if !ok {
+		return Credentials{}, fmt.Errorf("environment variable %s isn't set", e.UsernameVar)
+	}
+	password, ok := os.LookupEnv(e.PasswordVar)
+	if !ok {
+		return Credentials{}, fmt.Errorf("environment variable %s isn't set", e.PasswordVar)
+	}
+	return Credentials{Username: username, Password: contracts.Secret(password)}, nil
+}
+
+// FileCredentials reads the password, and optionally the username, from
+// files such as mounted secrets. Files are read on every call and trailing
+// whitespace is trimmed.
+type FileCredentials struct {
+	Username     string
+	UsernameFile string
+	PasswordFile string
+}
+
+func (f FileCredentials) Credentials(_ context.Context, _ DeviceIdentity) (Credentials, error) {
+	username := f.Username
+	if f.UsernameFile != "" {
+		b, err := os.ReadFile(f.UsernameFile)
+		if err != nil {
+			return Credentials{}, err
+		}
+		username = strings.TrimSpace(string(b))
+	}
+	b, err := os.ReadFile(f.PasswordFile)
+	if err != nil {
+		return Credentials{}, err
+	}
+	return Credentials{Username: username, Password: contracts.Secret(strings.TrimSpace(string(b)))}, nil
+}
+
+// DeviceCredentials picks a provider by device MAC or hostname, falling back
+// to Default when the device isn't listed. The MAC is looked up first, as
+// given, without separators or colon separated, in upper or lower case. The
+// hostname is looked up as given and in lower case.
+type DeviceCredentials struct {
+	Devices map[string]CredentialProvider
+	Default CredentialProvider
+}
+
+func (d DeviceCredentials) Credentials(ctx context.Context, device DeviceIdentity) (Credentials, error) {
+	if provider, ok := d.lookup(device); ok {
+		return provider.Credentials(ctx, device)
+	}
+	if d.Default == nil {
+		return Credentials{}, fmt.Errorf("no credentials for device %s", device.Host)
+	}
+	return d.Default.Credentials(ctx, device)
+}
+
+func (d DeviceCredentials) lookup(device DeviceIdentity) (CredentialProvider, bool) {
+	var keys []string
+	if device.MAC != "" {
+		mac := normalizeMAC(device.MAC)
+		colons := colonMAC(mac)
+		keys = append(keys, device.MAC, mac, strings.ToLower(mac), colons, strings.ToLower(colons))
+	}
+	if device.Host != "" {
+		keys = append(keys, device.Host, strings.ToLower(device.Host))
+	}
+	for _, key := range keys {
+		if provider, ok := d.Devices[key]; ok {
+			return provider, true
+		}
+	}
+	return nil, false
+}
+
+// normalizeMAC drops separators so A4:CF:12:F4:56:78 matches A4CF12F45678.
+func normalizeMAC(mac string) string {
+	return strings.ToUpper(strings.NewReplacer(":", "", "-", "", ".", "").Replace(mac))
+}
+
+// colonMAC formats a normalized MAC as A4:CF:12:F4:56:78.
+func colonMAC(mac string) string {
+	var b strings.Builder
+	for i := 0; i < len(mac); i += 2 {
+		if i > 0 {
+			b.WriteByte(':')
+		}
+		b.WriteString(mac[i:min(i+2, len(mac))])
+	}
+	return b.String()
+}
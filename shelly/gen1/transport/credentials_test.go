@@ -0,0 +1,185 @@
+package transport
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-faker/faker/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentialProviders(t *testing.T) {
+	dir := t.TempDir()
+	userFile := filepath.Join(dir, "username")
+	passFile := filepath.Join(dir, "password")
+	assert.NoError(t, os.WriteFile(userFile, []byte("file-user\n"), 0o600))
+	assert.NoError(t, os.WriteFile(passFile, []byte("file-pass\n"), 0o600))
+	t.Setenv("SHELLY_TEST_USER", "env-user")
+	t.Setenv("SHELLY_TEST_PASS", "env-pass")
+
+	type test struct {
+		title     string
+		provider  CredentialProvider
+		device    DeviceIdentity
+		want      Credentials
+		wantError bool
+	}
+	tests := []test{
+		{
+			title:    "Static credentials",
+			provider: StaticCredentials{Username: "admin", Password: "static"},
+			want:     Credentials{Username: "admin", Password: "static"},
+		},
+		{
+			title:    "Environment credentials",
+			provider: EnvCredentials{UsernameVar: "SHELLY_TEST_USER", PasswordVar: "SHELLY_TEST_PASS"},
+			want:     Credentials{Username: "env-user", Password: "env-pass"},
+		},
+		{
+			title:     "Missing environment variable",
+			provider:  EnvCredentials{UsernameVar: "SHELLY_TEST_USER", PasswordVar: "SHELLY_TEST_MISSING"},
+			wantError: true,
+		},
+		{
+			title:    "File credentials",
+			provider: FileCredentials{UsernameFile: userFile, PasswordFile: passFile},
+			want:     Credentials{Username: "file-user", Password: "file-pass"},
+		},
+		{
+			title:    "File password with static username",
+			provider: FileCredentials{Username: "admin", PasswordFile: passFile},
+			want:     Credentials{Username: "admin", Password: "file-pass"},
+		},
+		{
+			title:     "Missing password file",
+			provider:  FileCredentials{Username: "admin", PasswordFile: filepath.Join(dir, "missing")},
+			wantError: true,
+		},
+		{
+			title: "Device credentials by mac",
+			provider: DeviceCredentials{
+				Devices: map[string]CredentialProvider{
+					"a4:cf:12:f4:56:78": StaticCredentials{Username: "kitchen", Password: "k"},
+				},
+			},
+			device: DeviceIdentity{Host: "192.168.1.50", MAC: "A4CF12F45678"},
+			want:   Credentials{Username: "kitchen", Password: "k"},
+		},
+		{
+			title: "Device credentials by hostname",
+			provider: DeviceCredentials{
+				Devices: map[string]CredentialProvider{
+					"shelly1-abc123.local": StaticCredentials{Username: "garage", Password: "g"},
+				},
+			},
+			device: DeviceIdentity{Host: "Shelly1-ABC123.local"},
+			want:   Credentials{Username: "garage", Password: "g"},
+		},
+		{
+			title: "Device credentials by mac before hostname",
+			provider: DeviceCredentials{
+				Devices: map[string]CredentialProvider{
+					"shelly1-abc123.local": StaticCredentials{Username: "garage", Password: "g"},
+					"A4-CF-12-F4-56-78":    StaticCredentials{Username: "ignored", Password: "i"},
+					"A4CF12F45678":         StaticCredentials{Username: "kitchen", Password: "k"},
+				},
+			},
+			device: DeviceIdentity{Host: "shelly1-abc123.local", MAC: "a4:cf:12:f4:56:78"},
+			want:   Credentials{Username: "kitchen", Password: "k"},
+		},
+		{
+			title: "Device credentials fallback",
+			provider: DeviceCredentials{
+				Default: StaticCredentials{Username: "admin", Password: "default"},
+			},
+			device: DeviceIdentity{Host: "192.168.1.51"},
+			want:   Credentials{Username: "admin", Password: "default"},
+		},
+		{
+			title:     "Device credentials without fallback",
+			provider:  DeviceCredentials{},
+			device:    DeviceIdentity{Host: "192.168.1.51"},
+			wantError: true,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			creds, err := tc.provider.Credentials(context.Background(), tc.device)
+			if tc.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, creds)
+			}
+		})
+	}
+}
+
+func TestDeviceCredentialsDeterministic(t *testing.T) {
+	provider := DeviceCredentials{
+		Devices: map[string]CredentialProvider{
+			"192.168.1.50":      StaticCredentials{Username: "host", Password: "h"},
+			"a4:cf:12:f4:56:78": StaticCredentials{Username: "mac", Password: "m"},
+		},
+	}
+	for i := 0; i < 50; i++ {
+		creds, err := provider.Credentials(context.Background(), DeviceIdentity{Host: "192.168.1.50", MAC: "A4CF12F45678"})
+		assert.NoError(t, err)
+		assert.Equal(t, "mac", creds.Username)
+	}
+}
+
+func TestNewRestClientWithCredentials(t *testing.T) {
+	_, err := NewRestClient(ClientOptions{
+		Hostname:    "http://localhost",
+		Credentials: StaticCredentials{Username: "admin", Password: "secret"},
+	})
+	assert.ErrorIs(t, err, ErrCredentialsWithoutAuth)
+}
+
+func TestCredentialRotation(t *testing.T) {
+	passFile := filepath.Join(t.TempDir(), "password")
+	assert.NoError(t, os.WriteFile(passFile, []byte("first"), 0o600))
+
+	c, err := NewRestBasicAuthClient(ClientOptions{
+		Hostname:    "http://localhost",
+		Credentials: FileCredentials{Username: "admin", PasswordFile: passFile},
+	})
+	assert.NoError(t, err)
+
+	for _, password := range []string{"first", "second"} {
+		assert.NoError(t, os.WriteFile(passFile, []byte(password), 0o600))
+		req, err := c.NewRequest(http.MethodGet, "/settings", nil)
+		assert.NoError(t, err)
+		_, pass, ok := req.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, password, pass)
+	}
+}
+
+func TestResolveCredentialsIdentity(t *testing.T) {
+	baseURL, err := url.Parse("http://shelly1-abc123.local:8080")
+	assert.NoError(t, err)
+	var got DeviceIdentity
+	c := &Client{
+		BaseURL: baseURL,
+		mac:     "A4CF12F45678",
+		Credentials: providerFunc(func(ctx context.Context, device DeviceIdentity) (Credentials, error) {
+			got = device
+			return Credentials{Username: faker.Username(), Password: "secret"}, nil
+		}),
+	}
+	_, err = c.ResolveCredentials(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, DeviceIdentity{Host: "shelly1-abc123.local", MAC: "A4CF12F45678"}, got)
+}
+
+type providerFunc func(ctx context.Context, device DeviceIdentity) (Credentials, error)
+
+func (f providerFunc) Credentials(ctx context.Context, device DeviceIdentity) (Credentials, error) {
+	return f(ctx, device)
+}
@@ -0,0 +1,70 @@
+package transport
+
+import (
+	"net/url"
+
+	"github.com/google/go-querystring/query"
+)
+
+// Encoding defines how request parameters are sent to the device.
+type Encoding int
+
+const (
+	// EncodingJSON sends POST, PUT and PATCH parameters as a JSON body and GET
+	// parameters in the query string.
+	EncodingJSON Encoding = iota
+	// EncodingForm sends POST, PUT and PATCH parameters as an
+	// application/x-www-form-urlencoded body, which is what Gen1 firmware reads.
+	EncodingForm
+	// EncodingQuery sends the parameters in the query string for every method.
+	EncodingQuery
+)
+
+// Params wraps request parameters to override the client encoding for a
+// single request.
+type Params struct {
+	Encoding Encoding
+	Values   interface{}
+}
+
+// Form makes NewRequest send opts as a form encoded body.
+func Form(opts interface{}) Params {
+	return Params{Encoding: EncodingForm, Values: opts}
+}
+
+// Query makes NewRequest send opts in the query string whatever the method.
+func Query(opts interface{}) Params {
+	return Params{Encoding: EncodingQuery, Values: opts}
+}
+
+// requestParams unwraps opts and returns the encoding to use for them.
+func (c *Client) requestParams(opts interface{}) (Encoding, interface{}) {
+	if p, ok := opts.(Params); ok {
+		return p.Encoding, p.Values
+	}
+	return c.Encoding, opts
+}
+
+// encodeValues turns opts into url values. Structs are encoded with their
+// `url` tags and slices tagged with the brackets option become repeated keys
+// like schedule_rules[].
+//
+// Booleans follow the Gen1 convention, the literal true and false the
+// firmware documents for every boolean setting, e.g. led_status_disable=true.
+// That's the go-querystring default, so no conversion is needed. Parameters
+// sent as 1 and 0 take the int option, `url:"name,int"`, and optional
+// booleans should be *bool with omitempty, so that false is still sent while
+// nil is left out.
+func encodeValues(opts interface{}) (url.Values, error) {
+	switch v := opts.(type) {
+	case url.Values:
+		return v, nil
+	case map[string]string:
+		values := make(url.Values, len(v))
+		for k, value := range v {
+			values.Set(k, value)
+		}
+		return values, nil
+	}
+	return query.Values(opts)
+}
@@ -0,0 +1,123 @@
+package transport
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type scheduleOpts struct {
+	Schedule      bool     `url:"schedule"`
+	ScheduleRules []string `url:"schedule_rules,brackets"`
+	Name          string   `url:"name,omitempty"`
+}
+
+type ledOpts struct {
+	LedStatusDisable bool  `url:"led_status_disable"`
+	LedPowerDisable  *bool `url:"led_power_disable,omitempty"`
+	AutoOn           *bool `url:"auto_on,omitempty"`
+	Eco              bool  `url:"eco_mode_enabled,int"`
+}
+
+func TestNewRequestEncoding(t *testing.T) {
+	type test struct {
+		title           string
+		clientEncoding  Encoding
+		method          string
+		opts            interface{}
+		wantQuery       string
+		wantBody        string
+		wantContentType string
+	}
+	opts := &scheduleOpts{
+		Schedule:      true,
+		ScheduleRules: []string{"0800-0123456-on", "2000-0123456-off"},
+	}
+	encoded := "schedule=true&schedule_rules%5B%5D=0800-0123456-on&schedule_rules%5B%5D=2000-0123456-off"
+	tests := []test{
+		{
+			title:           "Json body by default",
+			method:          http.MethodPost,
+			opts:            &scheduleOpts{Name: "kitchen"},
+			wantBody:        `{"Schedule":false,"ScheduleRules":null,"Name":"kitchen"}`,
+			wantContentType: "application/json",
+		},
+		{
+			title:           "Form body from client encoding",
+			clientEncoding:  EncodingForm,
+			method:          http.MethodPost,
+			opts:            opts,
+			wantBody:        encoded,
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			title:           "Form body from request override",
+			method:          http.MethodPost,
+			opts:            Form(opts),
+			wantBody:        encoded,
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			title:     "Query string for post from request override",
+			method:    http.MethodPost,
+			opts:      Query(opts),
+			wantQuery: encoded,
+		},
+		{
+			title:     "Query string for get ignores form encoding",
+			method:    http.MethodGet,
+			opts:      Form(url.Values{"turn": []string{"on"}}),
+			wantQuery: "turn=on",
+		},
+		{
+			title:           "Form body booleans",
+			method:          http.MethodPost,
+			opts:            Form(&ledOpts{LedPowerDisable: new(bool), Eco: true}),
+			wantBody:        "eco_mode_enabled=1&led_power_disable=false&led_status_disable=false",
+			wantContentType: "application/x-www-form-urlencoded",
+		},
+		{
+			title:     "Query string booleans",
+			method:    http.MethodPost,
+			opts:      Query(&ledOpts{LedStatusDisable: true}),
+			wantQuery: "eco_mode_enabled=0&led_status_disable=true",
+		},
+		{
+			title:     "Map parameters",
+			method:    http.MethodGet,
+			opts:      map[string]string{"brightness": "50"},
+			wantQuery: "brightness=50",
+		},
+	}
+	baseURL, err := url.Parse("http://localhost")
+	assert.NoError(t, err)
+
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			c := &Client{BaseURL: baseURL, Encoding: tc.clientEncoding}
+			req, err := c.NewRequest(tc.method, "/settings", tc.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantQuery, req.URL.RawQuery)
+			if tc.wantContentType != "" {
+				assert.Equal(t, tc.wantContentType, req.Header.Get("Content-Type"))
+			}
+			body, err := req.BodyBytes()
+			assert.NoError(t, err)
+			if tc.wantBody == "" {
+				assert.Empty(t, body)
+			} else {
+				assert.Equal(t, tc.wantBody, string(body))
+			}
+		})
+	}
+}
+
+func TestNewRequestEncodingError(t *testing.T) {
+	baseURL, err := url.Parse("http://localhost")
+	assert.NoError(t, err)
+	c := &Client{BaseURL: baseURL}
+	_, err = c.NewRequest(http.MethodPost, "/settings", Form("invalid"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,230 @@
+package transport
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrLimiterBusy is returned when a request can't be admitted because every
+// slot is taken and queuing is disabled or the queue is full.
+var ErrLimiterBusy = errors.New("device request limit reached")
+
+// Priority defines the order in which queued requests are admitted.
+type Priority int
+
+const (
+	// PriorityBackground is meant for polling and other non urgent reads.
+	PriorityBackground Priority = iota - 1
+	// PriorityNormal is used when the request context carries no priority.
+	PriorityNormal
+	// PriorityControl is meant for commands that must jump ahead of polling.
+	PriorityControl
+)
+
+type priorityKey struct{}
+
+// WithPriority returns a copy of ctx that makes requests built with it
+// queue with the given priority. Pass it to NewRequestWithContext, or to the
+// service methods that take a context.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+func priorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityNormal
+}
+
+// LimiterOptions configures how many requests a single device receives.
+// Gen1 devices are ESP8266 based and misbehave with more than a couple of
+// parallel connections.
+type LimiterOptions struct {
+	// MaxInFlight is the number of concurrent requests. Zero means unlimited.
+	MaxInFlight int
+	// MinInterval is the minimum spacing between the start of two requests.
+	MinInterval time.Duration
+	// DisableQueue makes requests fail with ErrLimiterBusy instead of waiting
+	// for a free slot.
+	DisableQueue bool
+	// MaxQueue bounds the number of waiting requests. Zero means unbounded.
+	MaxQueue int
+}
+
+type waiter struct {
+	priority Priority
+	seq      uint64
+	ready    chan struct{}
+	index    int
+}
+
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	w.index = -1
+	*q = old[:n-1]
+	return w
+}
+
+type limiter struct {
+	opts     LimiterOptions
+	mu       sync.Mutex
+	inFlight int
+	next     time.Time
+	// freed holds the sorted start times given back by cancelled requests
+	// that were waiting for their turn, see space.
+	freed []time.Time
+	seq   uint64
+	queue waitQueue
+}
+
+func newLimiter(opts LimiterOptions) *limiter {
+	return &limiter{opts: opts}
+}
+
+// acquire blocks until the request is allowed to start and returns the
+// function that frees the slot once the request is done.
+func (l *limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	l.mu.Lock()
+	if l.opts.MaxInFlight <= 0 || (l.inFlight < l.opts.MaxInFlight && len(l.queue) == 0) {
+		l.inFlight++
+		l.mu.Unlock()
+		return l.space(ctx)
+	}
+	if l.opts.DisableQueue || (l.opts.MaxQueue > 0 && len(l.queue) >= l.opts.MaxQueue) {
+		l.mu.Unlock()
+		return nil, ErrLimiterBusy
+	}
+	l.seq++
+	w := &waiter{
+		priority: priorityFromContext(ctx),
+		seq:      l.seq,
+		ready:    make(chan struct{}),
+	}
+	heap.Push(&l.queue, w)
+	l.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		return l.space(ctx)
+	case <-ctx.Done():
+		l.mu.Lock()
+		granted := w.index < 0
+		if !granted {
+			heap.Remove(&l.queue, w.index)
+		}
+		l.mu.Unlock()
+		if granted {
+			l.release()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// space waits for the minimum interval between requests while holding a slot.
+func (l *limiter) space(ctx context.Context) (func(), error) {
+	if l.opts.MinInterval <= 0 {
+		return l.release, nil
+	}
+
+	l.mu.Lock()
+	start := l.reserve(time.Now())
+	l.mu.Unlock()
+
+	wait := time.Until(start)
+	if wait <= 0 {
+		return l.release, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return l.release, nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.unreserve(start)
+		l.mu.Unlock()
+		l.release()
+		return nil, ctx.Err()
+	}
+}
+
+// reserve returns the start time of a new request, reusing the earliest slot
+// freed by a cancelled request when it's still ahead.
+func (l *limiter) reserve(now time.Time) time.Time {
+	for len(l.freed) > 0 {
+		start := l.freed[0]
+		l.freed = l.freed[1:]
+		if !start.Before(now) {
+			return start
+		}
+	}
+	start := l.next
+	if start.Before(now) {
+		start = now
+	}
+	l.next = start.Add(l.opts.MinInterval)
+	return start
+}
+
+// unreserve gives back the slot of a request cancelled before its start, so
+// it doesn't push back the requests that come after it.
+func (l *limiter) unreserve(start time.Time) {
+	i, _ := slices.BinarySearchFunc(l.freed, start, time.Time.Compare)
+	l.freed = slices.Insert(l.freed, i, start)
+	// Trailing slots are dropped by moving next back instead.
+	for len(l.freed) > 0 {
+		last := l.freed[len(l.freed)-1]
+		if !last.Add(l.opts.MinInterval).Equal(l.next) {
+			break
+		}
+		l.next = last
+		l.freed = l.freed[:len(l.freed)-1]
+	}
+}
+
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	// Hand the slot straight to the next waiter so it can't be stolen.
+	if len(l.queue) > 0 {
+		w := heap.Pop(&l.queue).(*waiter)
+		close(w.ready)
+		return
+	}
+	l.inFlight--
+}
@@ -0,0 +1,184 @@
+package transport
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterMaxInFlight(t *testing.T) {
+	l := newLimiter(LimiterOptions{MaxInFlight: 2})
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := l.acquire(context.Background())
+			assert.NoError(t, err)
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			release()
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(2), peak)
+}
+
+func TestLimiterPriority(t *testing.T) {
+	l := newLimiter(LimiterOptions{MaxInFlight: 1})
+	release, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	order := make(chan Priority, 3)
+	var wg sync.WaitGroup
+	for _, p := range []Priority{PriorityBackground, PriorityNormal, PriorityControl} {
+		wg.Add(1)
+		go func(p Priority) {
+			defer wg.Done()
+			r, err := l.acquire(WithPriority(context.Background(), p))
+			assert.NoError(t, err)
+			order <- p
+			r()
+		}(p)
+		// make sure each waiter is queued before the next one
+		assert.Eventually(t, func() bool {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			return len(l.queue) == int(p)+2
+		}, time.Second, time.Millisecond)
+	}
+	release()
+	wg.Wait()
+	close(order)
+
+	var got []Priority
+	for p := range order {
+		got = append(got, p)
+	}
+	assert.Equal(t, []Priority{PriorityControl, PriorityNormal, PriorityBackground}, got)
+}
+
+func TestLimiterBusy(t *testing.T) {
+	type test struct {
+		title string
+		opts  LimiterOptions
+	}
+	tests := []test{
+		{
+			title: "Queue disabled",
+			opts:  LimiterOptions{MaxInFlight: 1, DisableQueue: true},
+		},
+		{
+			title: "Queue full",
+			opts:  LimiterOptions{MaxInFlight: 1, MaxQueue: 1},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			l := newLimiter(tc.opts)
+			release, err := l.acquire(context.Background())
+			assert.NoError(t, err)
+			defer release()
+
+			if tc.opts.MaxQueue > 0 {
+				ctx, cancel := context.WithCancel(context.Background())
+				defer cancel()
+				go func() {
+					_, _ = l.acquire(ctx)
+				}()
+				assert.Eventually(t, func() bool {
+					l.mu.Lock()
+					defer l.mu.Unlock()
+					return len(l.queue) == 1
+				}, time.Second, time.Millisecond)
+			}
+			_, err = l.acquire(context.Background())
+			assert.ErrorIs(t, err, ErrLimiterBusy)
+		})
+	}
+}
+
+func TestLimiterContextCancel(t *testing.T) {
+	l := newLimiter(LimiterOptions{MaxInFlight: 1})
+	release, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, l.queue)
+
+	release()
+	release, err = l.acquire(context.Background())
+	assert.NoError(t, err)
+	release()
+}
+
+func TestLimiterMinInterval(t *testing.T) {
+	l := newLimiter(LimiterOptions{MinInterval: 20 * time.Millisecond})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := l.acquire(context.Background())
+		assert.NoError(t, err)
+		release()
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestLimiterCanceledReservation(t *testing.T) {
+	l := newLimiter(LimiterOptions{MinInterval: time.Hour})
+	release, err := l.acquire(context.Background())
+	assert.NoError(t, err)
+	defer release()
+	l.mu.Lock()
+	next := l.next
+	l.mu.Unlock()
+
+	// Two requests wait for the next slots, cancelling the first one leaves a
+	// gap that's taken by the next request.
+	ctxs := make([]context.CancelFunc, 2)
+	done := make([]chan struct{}, 2)
+	for i := range ctxs {
+		ctx, cancel := context.WithCancel(context.Background())
+		ctxs[i], done[i] = cancel, make(chan struct{})
+		go func(i int) {
+			defer close(done[i])
+			_, err := l.acquire(ctx)
+			assert.ErrorIs(t, err, context.Canceled)
+		}(i)
+		assert.Eventually(t, func() bool {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			return l.next.Equal(next.Add(time.Duration(i+1) * time.Hour))
+		}, time.Second, time.Millisecond)
+	}
+	ctxs[0]()
+	<-done[0]
+	l.mu.Lock()
+	assert.Equal(t, []time.Time{next}, l.freed)
+	assert.Equal(t, next, l.reserve(time.Now()))
+	l.unreserve(next)
+	l.mu.Unlock()
+
+	// Cancelling the last one moves the next slot back to where it was.
+	ctxs[1]()
+	<-done[1]
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	assert.Equal(t, next, l.next)
+	assert.Empty(t, l.freed)
+}
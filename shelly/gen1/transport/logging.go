@@ -0,0 +1,122 @@
+package transport
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys holds the query, form and JSON keys Gen1 uses for passwords,
+// Wi-Fi keys and MQTT credentials.
+var sensitiveKeys = map[string]bool{
+	"password":  true,
+	"pass":      true,
+	"key":       true,
+	"user":      true,
+	"mqtt_user": true,
+	"mqtt_pass": true,
+}
+
+func isSensitive(key string) bool {
+	return sensitiveKeys[strings.ToLower(key)]
+}
+
+// discardHandler drops every record, it's used when no logger is configured.
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
+
+var discardLogger = slog.New(discardHandler{})
+
+// DiscardLogger returns a logger that drops every record, for the clients
+// built without a logger.
+func DiscardLogger() *slog.Logger {
+	return discardLogger
+}
+
+func (c *Client) log() *slog.Logger {
+	if c.logger == nil {
+		return discardLogger
+	}
+	return c.logger
+}
+
+// logRetry is used as the retryablehttp request hook, attempt zero is the
+// original request.
+func (c *Client) logRetry(_ retryablehttp.Logger, req *http.Request, attempt int) {
+	if attempt == 0 {
+		return
+	}
+	c.log().LogAttrs(req.Context(), slog.LevelWarn, "retrying request",
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL)),
+		slog.Int("attempt", attempt),
+	)
+}
+
+// redactURL returns the url as string with the secret query values masked.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	r := *u
+	r.User = nil
+	r.RawQuery = redactValues(u.Query()).Encode()
+	return r.String()
+}
+
+func redactValues(values url.Values) url.Values {
+	out := make(url.Values, len(values))
+	for k, v := range values {
+		if isSensitive(strings.TrimSuffix(k, "[]")) {
+			out[k] = []string{redacted}
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// redactPayload masks the secrets of a JSON or form encoded payload so it can
+// be safely logged.
+func redactPayload(body []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(body, &v); err == nil {
+		out, err := json.Marshal(redactJSON(v))
+		if err == nil {
+			return string(out)
+		}
+	}
+	if values, err := url.ParseQuery(string(body)); err == nil {
+		return redactValues(values).Encode()
+	}
+	return redacted
+}
+
+func redactJSON(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, value := range t {
+			if isSensitive(k) {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactJSON(value)
+		}
+	case []interface{}:
+		for i, value := range t {
+			t[i] = redactJSON(value)
+		}
+	}
+	return v
+}
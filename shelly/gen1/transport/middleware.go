@@ -0,0 +1,92 @@
+package transport
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+)
+
+// Handler sends a request to the device and returns the raw response.
+type Handler func(req *retryablehttp.Request) (*http.Response, error)
+
+// Middleware wraps a Handler to run code around every request and response.
+// Middlewares are applied in order, so the first one is the outermost.
+type Middleware func(next Handler) Handler
+
+// Use appends middlewares to the client chain.
+func (c *Client) Use(mw ...Middleware) {
+	c.middlewares = append(c.middlewares, mw...)
+}
+
+func (c *Client) handler() Handler {
+	h := Handler(c.client.Do)
+	if c.auth != nil {
+		h = c.auth(h)
+	}
+	return c.chain(h)
+}
+
+// chain wraps h with the client middlewares.
+func (c *Client) chain(h Handler) Handler {
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return h
+}
+
+// HeaderMiddleware sets the given headers on every request.
+func HeaderMiddleware(headers http.Header) Middleware {
+	return func(next Handler) Handler {
+		return func(req *retryablehttp.Request) (*http.Response, error) {
+			for k, v := range headers {
+				req.Header[k] = v
+			}
+			return next(req)
+		}
+	}
+}
+
+// TimingMiddleware calls observe with the outcome and duration of every
+// request, including the time spent on retries.
+func TimingMiddleware(observe func(req *retryablehttp.Request, resp *http.Response, elapsed time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return func(req *retryablehttp.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			observe(req, resp, time.Since(start), err)
+			return resp, err
+		}
+	}
+}
+
+// LoggingMiddleware logs every request with its status code and latency.
+func LoggingMiddleware(logger *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(req *retryablehttp.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next(req)
+			attrs := []slog.Attr{
+				slog.String("method", req.Method),
+				slog.String("url", redactURL(req.URL)),
+				slog.Duration("latency", time.Since(start)),
+			}
+			ctx := req.Context()
+			if err != nil {
+				logger.LogAttrs(ctx, slog.LevelError, "request failed", append(attrs, slog.Any("error", err))...)
+				return resp, err
+			}
+			attrs = append(attrs, slog.Int("status", resp.StatusCode))
+			logger.LogAttrs(ctx, levelForStatus(resp.StatusCode), "request completed", attrs...)
+			return resp, err
+		}
+	}
+}
+
+func levelForStatus(code int) slog.Level {
+	if code >= http.StatusBadRequest {
+		return slog.LevelWarn
+	}
+	return slog.LevelDebug
+}
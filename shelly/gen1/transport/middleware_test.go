@@ -0,0 +1,134 @@
+package transport
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupMiddlewareServer(t *testing.T, status int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Echo", r.Header.Get("X-Test"))
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"title":"testing"}`)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	server := setupMiddlewareServer(t, http.StatusOK)
+	var calls []string
+	record := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(req *retryablehttp.Request) (*http.Response, error) {
+				calls = append(calls, name+":before")
+				resp, err := next(req)
+				calls = append(calls, name+":after")
+				return resp, err
+			}
+		}
+	}
+
+	c, err := NewRestClient(ClientOptions{
+		Hostname:    server.URL,
+		Middlewares: []Middleware{record("first")},
+	})
+	assert.NoError(t, err)
+	c.Use(record("second"))
+
+	req, err := c.NewRequest(http.MethodGet, "/shelly", nil)
+	assert.NoError(t, err)
+	_, err = c.Do(req, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"first:before", "second:before", "second:after", "first:after"}, calls)
+}
+
+func TestHeaderMiddleware(t *testing.T) {
+	server := setupMiddlewareServer(t, http.StatusOK)
+	var echoed string
+	c, err := NewRestClient(ClientOptions{
+		Hostname: server.URL,
+		Middlewares: []Middleware{
+			HeaderMiddleware(http.Header{"X-Test": []string{"injected"}}),
+			TimingMiddleware(func(req *retryablehttp.Request, resp *http.Response, elapsed time.Duration, err error) {
+				echoed = resp.Header.Get("X-Echo")
+			}),
+		},
+	})
+	assert.NoError(t, err)
+
+	req, err := c.NewRequest(http.MethodGet, "/shelly", nil)
+	assert.NoError(t, err)
+	_, err = c.Do(req, &struct{}{})
+	assert.NoError(t, err)
+	assert.Equal(t, "injected", echoed)
+}
+
+func TestTimingMiddleware(t *testing.T) {
+	server := setupMiddlewareServer(t, http.StatusOK)
+	var elapsed time.Duration
+	var status int
+	c, err := NewRestClient(ClientOptions{
+		Hostname: server.URL,
+		Middlewares: []Middleware{
+			TimingMiddleware(func(req *retryablehttp.Request, resp *http.Response, d time.Duration, err error) {
+				elapsed = d
+				status = resp.StatusCode
+			}),
+		},
+	})
+	assert.NoError(t, err)
+
+	req, err := c.NewRequest(http.MethodGet, "/shelly", nil)
+	assert.NoError(t, err)
+	_, err = c.Do(req, &struct{}{})
+	assert.NoError(t, err)
+	assert.Greater(t, elapsed, time.Duration(0))
+	assert.Equal(t, http.StatusOK, status)
+}
+
+func TestLoggingMiddleware(t *testing.T) {
+	type test struct {
+		title   string
+		status  int
+		wantLog string
+	}
+	tests := []test{
+		{
+			title:   "Success is logged at debug level",
+			status:  http.StatusOK,
+			wantLog: "level=DEBUG msg=\"request completed\"",
+		},
+		{
+			title:   "Client errors are logged at warn level",
+			status:  http.StatusNotFound,
+			wantLog: "level=WARN msg=\"request completed\"",
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			server := setupMiddlewareServer(t, tc.status)
+			buf := &bytes.Buffer{}
+			logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+			c, err := NewRestClient(ClientOptions{
+				Hostname:    server.URL,
+				Middlewares: []Middleware{LoggingMiddleware(logger)},
+			})
+			assert.NoError(t, err)
+
+			req, err := c.NewRequest(http.MethodGet, "/shelly", nil)
+			assert.NoError(t, err)
+			_, _ = c.Do(req, &struct{}{})
+			assert.Contains(t, buf.String(), tc.wantLog)
+			assert.Contains(t, buf.String(), fmt.Sprintf("status=%d", tc.status))
+		})
+	}
+}
@@ -3,15 +3,15 @@ package transport
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"slices"
+	"sync/atomic"
 	"time"
 
-	"github.com/google/go-querystring/query"
 	"github.com/hashicorp/go-cleanhttp"
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
 	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
@@ -24,23 +24,63 @@ type ClientProxy interface {
 var _ contracts.ShellyClient = (*Client)(nil)
 
 type ClientOptions struct {
+	// Hostname is the device address, see ParseAddress for the accepted forms.
 	Hostname string
 	Username string
-	Password string
+	Password contracts.Secret
+	// Credentials replaces Username and Password with a provider that is
+	// asked for the device credentials on every request. It needs an auth
+	// client, NewRestClient returns ErrCredentialsWithoutAuth when it's set.
+	Credentials CredentialProvider
+	// MAC identifies the device for per device credential providers.
+	MAC string
+	// Limiter bounds the concurrency and rate of requests sent to the device.
+	Limiter *LimiterOptions
+	// Middlewares wrap every request sent by the client, see Middleware.
+	Middlewares []Middleware
+	// Logger receives the requests, retries, status codes and latencies.
+	// Secrets are redacted from every logged url and payload.
+	Logger *slog.Logger
+	// Encoding is the default encoding for request parameters, it can be
+	// overridden per request with Form and Query.
+	Encoding Encoding
+	// KeepBody stores the raw response body in contracts.Response.RawBody.
+	KeepBody bool
+	// Cache enables the per endpoint response cache, see CacheOptions.
+	Cache *CacheOptions
+	// Breaker enables the circuit breaker that fast fails requests with
+	// ErrDeviceOffline while the device is unreachable.
+	Breaker *BreakerOptions
 }
 
 // Client act's as the entry object for sdk
 type Client struct {
 	client       ClientProxy
-	Password     string
+	Password     contracts.Secret
 	Username     string
 	RequiresAuth bool
 	BaseURL      *url.URL
+	Encoding     Encoding
+	limiter      *limiter
+	middlewares  []Middleware
+	logger       *slog.Logger
+	keepBody     bool
+	cache        *responseCache
+	breaker      *breaker
+	probeClient  *http.Client
+	Credentials  CredentialProvider
+	mac          string
+	auth         Middleware
 }
 
-// NewClient creates a new http client instance in case the provided one is nil
+// NewClient creates a new http client instance in case the provided one is nil.
+// The client sends no credentials, so options with Credentials set are
+// rejected with ErrCredentialsWithoutAuth.
 func NewRestClient(options ClientOptions) (*Client, error) {
-	client, err := newClient(options.Hostname)
+	if options.Credentials != nil {
+		return nil, ErrCredentialsWithoutAuth
+	}
+	client, err := newClient(options)
 	if err != nil {
 		return nil, err
 	}
@@ -49,34 +89,67 @@ func NewRestClient(options ClientOptions) (*Client, error) {
 }
 
 func NewRestBasicAuthClient(opts ClientOptions) (*Client, error) {
-	client, err := newClient(opts.Hostname)
+	client, err := newClient(opts)
 	if err != nil {
 		return nil, err
 	}
 
 	client.Username = opts.Username
 	client.Password = opts.Password
+	client.Credentials = opts.Credentials
 	client.RequiresAuth = true
 	return client, nil
 }
 
-func newClient(hostname string) (*Client, error) {
-	baseURL, err := url.Parse(hostname)
+// NewRestCustomAuthClient creates a client that leaves authentication to the
+// middleware returned by auth instead of sending basic auth, like the Gen2
+// digest auth. The middleware runs closest to the device, after the client
+// middlewares, and can get the credentials from Client.ResolveCredentials.
+func NewRestCustomAuthClient(opts ClientOptions, auth func(c *Client) Middleware) (*Client, error) {
+	client, err := NewRestBasicAuthClient(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	client.auth = auth(client)
+	return client, nil
+}
+
+func newClient(opts ClientOptions) (*Client, error) {
+	address, err := ParseAddress(opts.Hostname)
 	if err != nil {
 		return nil, err
 	}
+	baseURL := address.URL()
 
 	c := &Client{}
 	c.BaseURL = baseURL
+	c.logger = opts.Logger
+	c.Encoding = opts.Encoding
+	c.keepBody = opts.KeepBody
+	c.mac = opts.MAC
 	// Configure the HTTP client.
 	c.client = &retryablehttp.Client{
-		ErrorHandler: retryablehttp.PassthroughErrorHandler,
-		HTTPClient:   cleanhttp.DefaultPooledClient(),
-		RetryWaitMin: 100 * time.Millisecond,
-		RetryWaitMax: 400 * time.Millisecond,
-		RetryMax:     5,
-		CheckRetry:   c.RetryHTTPCheck,
+		ErrorHandler:   retryablehttp.PassthroughErrorHandler,
+		HTTPClient:     cleanhttp.DefaultPooledClient(),
+		RetryWaitMin:   100 * time.Millisecond,
+		RetryWaitMax:   400 * time.Millisecond,
+		RetryMax:       5,
+		CheckRetry:     c.RetryHTTPCheck,
+		Backoff:        retryablehttp.DefaultBackoff,
+		RequestLogHook: c.requestHook,
+	}
+	if opts.Limiter != nil {
+		c.limiter = newLimiter(*opts.Limiter)
+	}
+	if opts.Cache != nil {
+		c.cache = newResponseCache(*opts.Cache)
 	}
+	if opts.Breaker != nil {
+		c.probeClient = cleanhttp.DefaultPooledClient()
+		c.breaker = newBreaker(baseURL.Host, *opts.Breaker, c.probeDevice)
+	}
+	c.Use(opts.Middlewares...)
 	return c, nil
 }
 
@@ -87,14 +160,40 @@ func (c *Client) RetryHTTPCheck(ctx context.Context, resp *http.Response, err er
 	if err != nil {
 		return false, err
 	}
-	if resp.StatusCode >= 500 {
+	if resp.StatusCode >= 500 && ctx.Value(noRetryKey{}) == nil {
 		return true, nil
 	}
 	return false, nil
 }
 
+type noRetryKey struct{}
+
+// WithoutRetry returns a copy of ctx that makes requests built with it fail
+// on the first 5xx answer instead of being retried, for commands that aren't
+// safe to send twice.
+func WithoutRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noRetryKey{}, true)
+}
+
+type applicationErrorKey struct{}
+
+// WithApplicationErrors returns a copy of ctx that makes requests built
+// with it pass the body of 5xx answers to isApplicationError. The answers
+// it accepts, like JSON-RPC error frames, are errors reported by a healthy
+// device and don't count against the breaker.
+func WithApplicationErrors(ctx context.Context, isApplicationError func(body []byte) bool) context.Context {
+	return context.WithValue(ctx, applicationErrorKey{}, isApplicationError)
+}
+
 func (c *Client) NewRequest(method, endpoint string, opts interface{}) (*retryablehttp.Request, error) {
-	jsonMethodsList := []string{
+	return c.NewRequestWithContext(context.Background(), method, endpoint, opts)
+}
+
+// NewRequestWithContext works like NewRequest but binds the request to ctx
+// before the credentials are resolved, so the provider sees the caller
+// deadline and cancellation.
+func (c *Client) NewRequestWithContext(ctx context.Context, method, endpoint string, opts interface{}) (*retryablehttp.Request, error) {
+	bodyMethodsList := []string{
 		http.MethodPatch,
 		http.MethodPost,
 		http.MethodPut,
@@ -106,26 +205,39 @@ func (c *Client) NewRequest(method, endpoint string, opts interface{}) (*retryab
 		return nil, err
 	}
 
+	encoding, params := c.requestParams(opts)
 	reqHeaders := make(http.Header)
 	reqHeaders.Set("Accept", "application/json")
-	if slices.Contains(jsonMethodsList, method) {
-		reqHeaders.Set("Content-Type", "application/json")
-		if opts != nil {
-			body, err = json.Marshal(opts)
-			if err != nil {
-				return nil, err
+	if slices.Contains(bodyMethodsList, method) {
+		switch encoding {
+		case EncodingForm:
+			reqHeaders.Set("Content-Type", "application/x-www-form-urlencoded")
+			if params != nil {
+				values, err := encodeValues(params)
+				if err != nil {
+					return nil, err
+				}
+				body = []byte(values.Encode())
+			}
+		case EncodingJSON:
+			reqHeaders.Set("Content-Type", "application/json")
+			if params != nil {
+				body, err = json.Marshal(params)
+				if err != nil {
+					return nil, err
+				}
 			}
 		}
 	}
 
-	request, err := retryablehttp.NewRequest(method, u, body)
+	request, err := retryablehttp.NewRequestWithContext(ctx, method, u, body)
 	if err != nil {
 		return nil, err
 	}
 
 	c.SetAdditionalHeaders(request, reqHeaders)
 
-	if c.RequiresAuth {
+	if c.RequiresAuth && c.auth == nil {
 		err = c.SetBasicAuth(request)
 		if err != nil {
 			return nil, err
@@ -146,8 +258,9 @@ func (c *Client) ParseUrl(method, endpoint string, opts interface{}) (string, er
 	u.RawPath = c.BaseURL.Path + endpoint
 	u.Path = c.BaseURL.Path + unescaped
 
-	if method == http.MethodGet && opts != nil {
-		q, err := query.Values(opts)
+	encoding, params := c.requestParams(opts)
+	if params != nil && (method == http.MethodGet || encoding == EncodingQuery) {
+		q, err := encodeValues(params)
 		if err != nil {
 			return "", err
 		}
@@ -164,44 +277,201 @@ func (c *Client) SetAdditionalHeaders(request *retryablehttp.Request, headers ht
 }
 
 func (c *Client) SetBasicAuth(request *retryablehttp.Request) error {
-	if c.Username == "" {
-		return fmt.Errorf("username can't be empty")
-	}
-	if c.Password == "" {
-		return fmt.Errorf("password can't be empty")
+	creds, err := c.ResolveCredentials(requestContext(request))
+	if err != nil {
+		return err
 	}
-	request.SetBasicAuth(c.Username, c.Password)
+	request.SetBasicAuth(creds.Username, creds.Password.Reveal())
 	return nil
 }
 
+// ResolveCredentials returns the device credentials from the configured
+// provider, or from Username and Password when there's none.
+func (c *Client) ResolveCredentials(ctx context.Context) (Credentials, error) {
+	if err := ctx.Err(); err != nil {
+		return Credentials{}, err
+	}
+	var provider CredentialProvider = StaticCredentials{Username: c.Username, Password: c.Password}
+	if c.Credentials != nil {
+		provider = c.Credentials
+	}
+	device := DeviceIdentity{MAC: c.mac}
+	if c.BaseURL != nil {
+		device.Host = c.BaseURL.Hostname()
+	}
+	creds, err := provider.Credentials(ctx, device)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if creds.Username == "" {
+		return Credentials{}, fmt.Errorf("username can't be empty")
+	}
+	if creds.Password == "" {
+		return Credentials{}, fmt.Errorf("password can't be empty")
+	}
+	return creds, nil
+}
+
 func (c *Client) Do(req *retryablehttp.Request, v interface{}) (*contracts.Response, error) {
-	resp, err := c.client.Do(req)
+	ctx := requestContext(req)
+	var cacheKey string
+	var cacheable bool
+	var generation uint64
+	if c.cache != nil {
+		cacheKey, cacheable = c.cache.cacheKey(req, c.BaseURL.Path)
+		if cacheable {
+			if entry, ok := c.cache.get(cacheKey); ok {
+				return c.cachedResponse(req, entry, v)
+			}
+			generation = c.cache.currentGeneration()
+		}
+	}
+
+	trial, err := c.breaker.allow()
 	if err != nil {
 		return nil, err
 	}
+	release, err := c.limiter.acquire(ctx)
+	if err != nil {
+		c.breaker.abort(trial)
+		return nil, err
+	}
+	defer release()
 
-	switch os := resp.StatusCode; os {
+	logger := c.log()
+	c.logRequest(ctx, req)
+	attempts := new(int32)
+	if req.Request != nil {
+		req = req.WithContext(context.WithValue(ctx, attemptsKey{}, attempts))
+	}
+	start := time.Now()
+	resp, err := c.handler()(req)
+	latency := time.Since(start)
+	var body []byte
+	if err == nil && resp.Body != nil {
+		defer resp.Body.Close()
+		// read upfront, the breaker may need it to tell device errors apart
+		body, err = io.ReadAll(resp.Body)
+	}
+	c.recordOutcome(ctx, trial, resp, body, err)
+	if err != nil {
+		logger.LogAttrs(ctx, slog.LevelError, "request failed",
+			append(requestAttrs(req), slog.Duration("latency", latency), slog.Any("error", err))...)
+		return nil, err
+	}
+	logger.LogAttrs(ctx, levelForStatus(resp.StatusCode), "response received",
+		append(requestAttrs(req), slog.Int("status", resp.StatusCode), slog.Duration("latency", latency))...)
+
+	switch resp.StatusCode {
+	case http.StatusInternalServerError, http.StatusUnauthorized:
+		return nil, &StatusError{StatusCode: resp.StatusCode, Header: resp.Header, Body: body}
+	}
+
+	if len(body) > 0 && logger.Enabled(ctx, slog.LevelDebug) {
+		logger.LogAttrs(ctx, slog.LevelDebug, "response payload",
+			append(requestAttrs(req), slog.String("body", redactPayload(body)))...)
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, v); err != nil {
+			return nil, err
+		}
+	}
+
+	response := &contracts.Response{
+		Response:   resp,
+		Latency:    latency,
+		Attempts:   max(int(atomic.LoadInt32(attempts)), 1),
+		FinalURL:   finalURL(req, resp),
+		DeviceTime: deviceTime(body),
+	}
+	if c.keepBody {
+		response.RawBody = body
+	}
+	if c.cache != nil && resp.StatusCode < http.StatusBadRequest {
+		if cacheable {
+			c.cache.set(cacheKey, generation, resp, body)
+		} else if isWrite(req) {
+			c.cache.invalidate()
+		}
+	}
+	return response, nil
+}
+
+// StatusError is returned when the device answers with a status the client
+// treats as a failure. Body holds the response body.
+type StatusError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	switch e.StatusCode {
 	case http.StatusInternalServerError:
-		return nil, errors.New("server error")
+		return "server error"
 	case http.StatusUnauthorized:
-		return nil, errors.New("unauthorized to access this resource")
+		return "unauthorized to access this resource"
 	}
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
 
-	defer func() {
-		err = resp.Body.Close()
-	}()
+func (c *Client) logRequest(ctx context.Context, req *retryablehttp.Request) {
+	logger := c.log()
+	if req.Request == nil || !logger.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := requestAttrs(req)
+	if body, err := req.BodyBytes(); err == nil && len(body) > 0 {
+		attrs = append(attrs, slog.String("body", redactPayload(body)))
+	}
+	logger.LogAttrs(ctx, slog.LevelDebug, "sending request", attrs...)
+}
 
-	defer func() {
-		_, err = io.Copy(io.Discard, resp.Body)
-	}()
+func requestAttrs(req *retryablehttp.Request) []slog.Attr {
+	if req.Request == nil {
+		return nil
+	}
+	return []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", redactURL(req.URL)),
+	}
+}
 
-	err = json.NewDecoder(resp.Body).Decode(v)
-	if err == io.EOF {
-		err = nil
+func requestContext(req *retryablehttp.Request) context.Context {
+	if req.Request == nil {
+		return context.Background()
 	}
-	if err != nil {
-		return nil, err
+	return req.Context()
+}
+
+type attemptsKey struct{}
+
+// requestHook runs before every attempt made by the retryable client.
+func (c *Client) requestHook(l retryablehttp.Logger, req *http.Request, attempt int) {
+	if counter, ok := req.Context().Value(attemptsKey{}).(*int32); ok {
+		atomic.AddInt32(counter, 1)
 	}
+	c.logRetry(l, req, attempt)
+}
 
-	return &contracts.Response{Response: resp}, nil
+func finalURL(req *retryablehttp.Request, resp *http.Response) *url.URL {
+	if resp.Request != nil {
+		return resp.Request.URL
+	}
+	if req.Request != nil {
+		return req.URL
+	}
+	return nil
+}
+
+// deviceTime reads the unixtime field that Gen1 includes in /status and
+// /settings responses.
+func deviceTime(body []byte) time.Time {
+	var payload struct {
+		Unixtime int64 `json:"unixtime"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.Unixtime <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(payload.Unixtime, 0)
 }
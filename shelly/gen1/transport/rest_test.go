@@ -2,17 +2,20 @@ package transport
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 	"time"
 
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
 	"github.com/rubemlrm/go-shelly/shelly/gen1/transport/mocks"
 	"github.com/stretchr/testify/mock"
 
@@ -35,7 +38,7 @@ func (m *MockContext) Done() <-chan struct{} {
 }
 
 func (m *MockContext) Value(key any) any {
-	panic("implement me")
+	return nil
 }
 
 func (m *MockContext) Err() error {
@@ -47,6 +50,7 @@ func TestNewClient(t *testing.T) {
 		title     string
 		wantError bool
 		input     ClientOptions
+		want      string
 	}
 
 	tests := []test{
@@ -63,6 +67,7 @@ func TestNewClient(t *testing.T) {
 			input: ClientOptions{
 				Hostname: "localhost",
 			},
+			want: "http://localhost",
 		},
 	}
 
@@ -73,7 +78,7 @@ func TestNewClient(t *testing.T) {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tc.input.Hostname, c.BaseURL.String())
+				assert.Equal(t, tc.want, c.BaseURL.String())
 			}
 		})
 	}
@@ -100,7 +105,7 @@ func TestNewBasicAuthClient(t *testing.T) {
 			wantError: false,
 			input: ClientOptions{
 				Username: faker.Username(),
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				Hostname: faker.URL(),
 			},
 		},
@@ -113,12 +118,30 @@ func TestNewBasicAuthClient(t *testing.T) {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
-				assert.Equal(t, tc.input.Hostname, c.BaseURL.String())
+				assert.Equal(t, strings.TrimSuffix(tc.input.Hostname, "/"), c.BaseURL.String())
 			}
 		})
 	}
 }
 
+func TestClientFormattingRedactsPassword(t *testing.T) {
+	opts := ClientOptions{
+		Hostname:    "http://192.168.1.50",
+		Username:    "admin",
+		Password:    "hunter2",
+		Credentials: StaticCredentials{Username: "admin", Password: "hunter2"},
+	}
+	c, err := NewRestBasicAuthClient(opts)
+	assert.NoError(t, err)
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		t.Run(format, func(t *testing.T) {
+			assert.NotContains(t, fmt.Sprintf(format, opts), "hunter2")
+			assert.NotContains(t, fmt.Sprintf(format, c), "hunter2")
+			assert.NotContains(t, fmt.Sprintf(format, *c), "hunter2")
+		})
+	}
+}
+
 func TestRetryHTTPCheck(t *testing.T) {
 	type test struct {
 		title            string
@@ -135,7 +158,7 @@ func TestRetryHTTPCheck(t *testing.T) {
 			title: "Context Error found",
 			input: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 			},
 			wantError:        true,
@@ -149,7 +172,7 @@ func TestRetryHTTPCheck(t *testing.T) {
 			title: "Error passed to method",
 			input: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 			},
 			wantError:        true,
@@ -163,7 +186,7 @@ func TestRetryHTTPCheck(t *testing.T) {
 			title: "HTTP response retrieves a 5xx error",
 			input: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 			},
 			wantError:    false,
@@ -179,7 +202,7 @@ func TestRetryHTTPCheck(t *testing.T) {
 			title: "HTTP response retrieves a 200 code",
 			input: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 			},
 			wantError:    false,
@@ -212,6 +235,14 @@ func TestRetryHTTPCheck(t *testing.T) {
 	}
 }
 
+func TestRetryHTTPCheckWithoutRetry(t *testing.T) {
+	c := &Client{}
+	resp := &http.Response{StatusCode: http.StatusInternalServerError}
+	retry, err := c.RetryHTTPCheck(WithoutRetry(context.Background()), resp, nil)
+	assert.NoError(t, err)
+	assert.False(t, retry)
+}
+
 func TestNewRequest(t *testing.T) {
 
 	type Opts struct {
@@ -237,7 +268,7 @@ func TestNewRequest(t *testing.T) {
 			endpoint: "/random",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 				BaseURL:  url,
 			},
@@ -251,7 +282,7 @@ func TestNewRequest(t *testing.T) {
 			endpoint: "/random",
 			client: &Client{
 				Username:     faker.Username(),
-				Password:     faker.Password(),
+				Password:     contracts.Secret(faker.Password()),
 				client:       &retryablehttp.Client{},
 				BaseURL:      url,
 				RequiresAuth: true,
@@ -266,7 +297,7 @@ func TestNewRequest(t *testing.T) {
 			endpoint: "/random",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 				BaseURL:  url,
 			},
@@ -281,7 +312,7 @@ func TestNewRequest(t *testing.T) {
 			endpoint: "/%%xpto",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 				BaseURL:  url,
 			},
@@ -296,7 +327,7 @@ func TestNewRequest(t *testing.T) {
 			endpoint: "/test",
 			client: &Client{
 				Username:     "",
-				Password:     faker.Password(),
+				Password:     contracts.Secret(faker.Password()),
 				client:       &retryablehttp.Client{},
 				BaseURL:      url,
 				RequiresAuth: true,
@@ -311,7 +342,7 @@ func TestNewRequest(t *testing.T) {
 			endpoint: "/test",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 				BaseURL:  url,
 			},
@@ -326,7 +357,7 @@ func TestNewRequest(t *testing.T) {
 			endpoint: "/test",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 				BaseURL:  url,
 			},
@@ -371,7 +402,7 @@ func TestSetBasicAuth(t *testing.T) {
 			title: "Username can't be empty",
 			input: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 			},
 			wantError:    true,
@@ -391,7 +422,7 @@ func TestSetBasicAuth(t *testing.T) {
 			title: "Auth set with success",
 			input: &Client{
 				Username: faker.Username(),
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 				client:   &retryablehttp.Client{},
 			},
 			wantError:    false,
@@ -443,7 +474,7 @@ func TestDo(t *testing.T) {
 			title: "Test response error",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 			},
 			mockClientReturn: mockClientReturn{
 				response: nil,
@@ -455,7 +486,7 @@ func TestDo(t *testing.T) {
 			title: "Test response status error code",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 			},
 			mockClientReturn: mockClientReturn{
 				response: &http.Response{StatusCode: http.StatusInternalServerError},
@@ -467,7 +498,7 @@ func TestDo(t *testing.T) {
 			title: "Test response status error code",
 			client: &Client{
 				Username: faker.Username(),
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 			},
 			mockClientReturn: mockClientReturn{
 				response: &http.Response{StatusCode: http.StatusUnauthorized},
@@ -479,7 +510,7 @@ func TestDo(t *testing.T) {
 			title: "Test Failed response decode",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 			},
 			mockClientReturn: mockClientReturn{
 				response: &http.Response{
@@ -494,7 +525,7 @@ func TestDo(t *testing.T) {
 			title: "Test Response output",
 			client: &Client{
 				Username: "",
-				Password: faker.Password(),
+				Password: contracts.Secret(faker.Password()),
 			},
 			mockClientReturn: mockClientReturn{
 				response: &http.Response{
@@ -525,3 +556,82 @@ func TestDo(t *testing.T) {
 		})
 	}
 }
+
+func TestDoResponseMetadata(t *testing.T) {
+	type test struct {
+		title          string
+		keepBody       bool
+		failures       int32
+		body           string
+		wantAttempts   int
+		wantDeviceTime time.Time
+	}
+	tests := []test{
+		{
+			title:          "Metadata without raw body",
+			body:           `{"unixtime":1700000000}`,
+			wantAttempts:   1,
+			wantDeviceTime: time.Unix(1700000000, 0),
+		},
+		{
+			title:        "Metadata with raw body and retries",
+			keepBody:     true,
+			failures:     2,
+			body:         `{"status":"ok"}`,
+			wantAttempts: 3,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			var calls int32
+			mux := http.NewServeMux()
+			mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls <= tc.failures {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				fmt.Fprint(w, tc.body)
+			})
+			mux.HandleFunc("/old", func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/status", http.StatusFound)
+			})
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+
+			c, err := NewRestClient(ClientOptions{Hostname: server.URL, KeepBody: tc.keepBody})
+			assert.NoError(t, err)
+			req, err := c.NewRequest(http.MethodGet, "/old", nil)
+			assert.NoError(t, err)
+			resp, err := c.Do(req, &map[string]interface{}{})
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.wantAttempts, resp.Attempts)
+			assert.Greater(t, resp.Latency, time.Duration(0))
+			assert.Equal(t, server.URL+"/status", resp.FinalURL.String())
+			assert.Equal(t, tc.wantDeviceTime, resp.DeviceTime)
+			if tc.keepBody {
+				assert.Equal(t, tc.body, string(resp.RawBody))
+			} else {
+				assert.Nil(t, resp.RawBody)
+			}
+		})
+	}
+}
+
+func TestDoStatusError(t *testing.T) {
+	mockClient := mocks.NewClientProxy(t)
+	mockClient.On("Do", mock.Anything).Return(&http.Response{
+		StatusCode: http.StatusUnauthorized,
+		Header:     http.Header{"Www-Authenticate": []string{`Digest realm="shelly"`}},
+		Body:       io.NopCloser(strings.NewReader(`{"code":401}`)),
+	}, nil)
+	c := &Client{client: mockClient}
+
+	_, err := c.Do(&retryablehttp.Request{}, &map[string]interface{}{})
+	var statusErr *StatusError
+	assert.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, "unauthorized to access this resource", err.Error())
+	assert.Equal(t, `{"code":401}`, string(statusErr.Body))
+	assert.Equal(t, `Digest realm="shelly"`, statusErr.Header.Get("WWW-Authenticate"))
+}
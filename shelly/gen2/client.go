@@ -0,0 +1,67 @@
+package gen2
+
+import (
+	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/rubemlrm/go-shelly/shelly/gen2/components"
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+)
+
+type RestClient struct {
+	RPC    *rpc.HTTPClient
+	Switch *components.SwitchService
+	Cover  *components.CoverService
+	Light  *components.LightService
+	RGB    *components.RGBService
+	RGBW   *components.RGBWService
+	client *transport.Client
+}
+
+func NewRestClient(options transport.ClientOptions) (*RestClient, error) {
+	cl, err := transport.NewRestClient(options)
+	if err != nil {
+		return nil, err
+	}
+	return newRestClient(cl), nil
+}
+
+// NewRestClientWithAuth creates a client that answers the device digest
+// challenges, the username defaults to admin. Gen2 devices have no other
+// user, so any other username fails with rpc.ErrDigestUsername.
+func NewRestClientWithAuth(options transport.ClientOptions) (*RestClient, error) {
+	if options.Username == "" {
+		options.Username = rpc.DigestUsername
+	}
+	if options.Username != rpc.DigestUsername {
+		return nil, rpc.ErrDigestUsername
+	}
+	cl, err := transport.NewRestCustomAuthClient(options, rpc.DigestAuth)
+	if err != nil {
+		return nil, err
+	}
+	return newRestClient(cl), nil
+}
+
+func newRestClient(cl *transport.Client) *RestClient {
+	caller := rpc.NewHTTPClient(cl)
+	return &RestClient{
+		RPC:    caller,
+		Switch: components.NewSwitchService(caller),
+		Cover:  components.NewCoverService(caller),
+		Light:  components.NewLightService(caller),
+		RGB:    components.NewRGBService(caller),
+		RGBW:   components.NewRGBWService(caller),
+		client: cl,
+	}
+}
+
+// BreakerState returns the state of the device circuit, see
+// transport.Client.BreakerState.
+func (c *RestClient) BreakerState() transport.BreakerState {
+	return c.client.BreakerState()
+}
+
+// Close stops the background work of the client, like the circuit breaker
+// probe.
+func (c *RestClient) Close() {
+	c.client.Close()
+}
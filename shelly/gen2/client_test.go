@@ -0,0 +1,69 @@
+package gen2
+
+import (
+	"testing"
+
+	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRestClient(t *testing.T) {
+	type args struct {
+		options transport.ClientOptions
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantErr bool
+	}{
+		{
+			name: "Test Client creation with sucess",
+			args: args{
+				options: transport.ClientOptions{
+					Hostname: "http://localhost",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Test Client creation with error",
+			args: args{
+				options: transport.ClientOptions{
+					Hostname: "http://»%@ 2 2.com",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewRestClient(tt.args.options)
+			if tt.wantErr {
+				assert.NotNil(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, client.RPC)
+				assert.Equal(t, client.RPC, client.Switch.Caller)
+				assert.Equal(t, client.RPC, client.Cover.Caller)
+				assert.Equal(t, client.RPC, client.Light.Caller)
+				assert.Equal(t, client.RPC, client.RGB.Caller)
+				assert.Equal(t, client.RPC, client.RGBW.Caller)
+				assert.Equal(t, transport.BreakerClosed, client.BreakerState())
+				client.Close()
+			}
+		})
+	}
+}
+
+func TestNewRestClientWithAuth(t *testing.T) {
+	client, err := NewRestClientWithAuth(transport.ClientOptions{Hostname: "http://localhost", Password: "secret"})
+	assert.NoError(t, err)
+	assert.NotNil(t, client.RPC)
+
+	_, err = NewRestClientWithAuth(transport.ClientOptions{Hostname: "http://»%@ 2 2.com"})
+	assert.NotNil(t, err)
+
+	_, err = NewRestClientWithAuth(transport.ClientOptions{Hostname: "http://localhost", Username: "user", Password: "secret"})
+	assert.ErrorIs(t, err, rpc.ErrDigestUsername)
+}
@@ -0,0 +1,44 @@
+package components
+
+import (
+	"context"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+)
+
+// Energy is an energy counter, ByMinute holds the milliwatt-hours of the
+// last three complete minutes, the latest first.
+type Energy struct {
+	Total    float64   `json:"total"`
+	ByMinute []float64 `json:"by_minute,omitempty"`
+	MinuteTs int64     `json:"minute_ts,omitempty"`
+}
+
+// Temperature is an internal temperature reading, it's nil when the sensor
+// isn't available.
+type Temperature struct {
+	C *float64 `json:"tC"`
+	F *float64 `json:"tF"`
+}
+
+// SetConfigResponse is returned by every SetConfig method.
+type SetConfigResponse struct {
+	RestartRequired bool `json:"restart_required"`
+}
+
+type idParams struct {
+	ID int `json:"id"`
+}
+
+type setConfigParams struct {
+	ID     int         `json:"id"`
+	Config interface{} `json:"config"`
+}
+
+func call[T any](ctx context.Context, caller rpc.Caller, method string, params interface{}) (*T, error) {
+	var out T
+	if err := caller.Call(ctx, method, params, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
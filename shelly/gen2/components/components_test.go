@@ -0,0 +1,49 @@
+package components
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	transport "github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// SetupCaller serves /rpc and checks that every frame calls method with the
+// wanted params, result is sent back as the frame result.
+func SetupCaller(t *testing.T, method, wantParams, result string) *rpc.HTTPClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     uint64          `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, method, req.Method)
+		assert.JSONEq(t, wantParams, string(req.Params))
+		fmt.Fprintf(w, `{"id":%d,"result":%s}`, req.ID, result)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := transport.NewRestClient(transport.ClientOptions{Hostname: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return rpc.NewHTTPClient(client)
+}
+
+func fixture(path string) string {
+	b, err := os.ReadFile("./testdata/" + path)
+	if err != nil {
+		panic(err)
+	}
+	return string(b)
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,198 @@
+package components
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+)
+
+// CoverState is the state reported in CoverStatus.State.
+type CoverState string
+
+const (
+	CoverOpen        CoverState = "open"
+	CoverClosed      CoverState = "closed"
+	CoverOpening     CoverState = "opening"
+	CoverClosing     CoverState = "closing"
+	CoverStopped     CoverState = "stopped"
+	CoverCalibrating CoverState = "calibrating"
+)
+
+// Moving tells whether the motor is running.
+func (s CoverState) Moving() bool {
+	return s == CoverOpening || s == CoverClosing || s == CoverCalibrating
+}
+
+// Errors reported in CoverStatus.Errors, on top of the SwitchError ones.
+const (
+	CoverErrorObstruction          = "obstruction"
+	CoverErrorSafetySwitch         = "safety_switch"
+	CoverErrorWrongDirection       = "bad_feedback:rotating_in_wrong_direction"
+	CoverErrorBothDirectionsActive = "bad_feedback:both_directions_active"
+)
+
+// Input modes of CoverConfig.InMode, InModeDetached applies too.
+const (
+	CoverInModeSingle = "single"
+	CoverInModeDual   = "dual"
+)
+
+// Initial states of CoverConfig.InitialState.
+const (
+	CoverInitialOpen    = "open"
+	CoverInitialClosed  = "closed"
+	CoverInitialStopped = "stopped"
+)
+
+// Directions and actions of the obstruction detection and safety switch.
+const (
+	CoverDirectionOpen  = "open"
+	CoverDirectionClose = "close"
+	CoverDirectionBoth  = "both"
+	CoverActionStop     = "stop"
+	CoverActionReverse  = "reverse"
+	CoverActionPause    = "pause"
+)
+
+type CoverStatus struct {
+	ID            int          `json:"id"`
+	Source        string       `json:"source"`
+	State         CoverState   `json:"state"`
+	APower        *float64     `json:"apower,omitempty"`
+	Voltage       *float64     `json:"voltage,omitempty"`
+	Current       *float64     `json:"current,omitempty"`
+	PF            *float64     `json:"pf,omitempty"`
+	Freq          *float64     `json:"freq,omitempty"`
+	AEnergy       *Energy      `json:"aenergy,omitempty"`
+	CurrentPos    *int         `json:"current_pos,omitempty"`
+	TargetPos     *int         `json:"target_pos,omitempty"`
+	SlatPos       *int         `json:"slat_pos,omitempty"`
+	MoveTimeout   *float64     `json:"move_timeout,omitempty"`
+	MoveStartedAt *float64     `json:"move_started_at,omitempty"`
+	PosControl    bool         `json:"pos_control"`
+	LastDirection *string      `json:"last_direction,omitempty"`
+	Temperature   *Temperature `json:"temperature,omitempty"`
+	Errors        []string     `json:"errors,omitempty"`
+}
+
+// HasError tells whether the cover reports the given error.
+func (s *CoverStatus) HasError(err string) bool {
+	return slices.Contains(s.Errors, err)
+}
+
+type CoverMotorConfig struct {
+	IdlePowerThr      *float64 `json:"idle_power_thr,omitempty"`
+	IdleConfirmPeriod *float64 `json:"idle_confirm_period,omitempty"`
+}
+
+type CoverSafetySwitchConfig struct {
+	Enable      *bool   `json:"enable,omitempty"`
+	Direction   string  `json:"direction,omitempty"`
+	Action      string  `json:"action,omitempty"`
+	AllowedMove *string `json:"allowed_move,omitempty"`
+}
+
+type CoverObstructionConfig struct {
+	Enable    *bool    `json:"enable,omitempty"`
+	Direction string   `json:"direction,omitempty"`
+	Action    string   `json:"action,omitempty"`
+	PowerThr  *float64 `json:"power_thr,omitempty"`
+	Holdoff   *float64 `json:"holdoff,omitempty"`
+}
+
+// CoverSlatConfig is available on firmware with slat (tilt) control.
+type CoverSlatConfig struct {
+	Enable     *bool    `json:"enable,omitempty"`
+	OpenTime   *float64 `json:"open_time,omitempty"`
+	CloseTime  *float64 `json:"close_time,omitempty"`
+	Step       *int     `json:"step,omitempty"`
+	RetainPos  *bool    `json:"retain_pos,omitempty"`
+	PreciseCtl *bool    `json:"precise_ctl,omitempty"`
+}
+
+// CoverConfig fields left nil are not changed by SetConfig.
+type CoverConfig struct {
+	ID                   int                      `json:"id,omitempty"`
+	Name                 *string                  `json:"name,omitempty"`
+	Motor                *CoverMotorConfig        `json:"motor,omitempty"`
+	MaxtimeOpen          *float64                 `json:"maxtime_open,omitempty"`
+	MaxtimeClose         *float64                 `json:"maxtime_close,omitempty"`
+	InitialState         string                   `json:"initial_state,omitempty"`
+	InvertDirections     *bool                    `json:"invert_directions,omitempty"`
+	InMode               string                   `json:"in_mode,omitempty"`
+	SwapInputs           *bool                    `json:"swap_inputs,omitempty"`
+	SafetySwitch         *CoverSafetySwitchConfig `json:"safety_switch,omitempty"`
+	PowerLimit           *float64                 `json:"power_limit,omitempty"`
+	VoltageLimit         *float64                 `json:"voltage_limit,omitempty"`
+	UndervoltageLimit    *float64                 `json:"undervoltage_limit,omitempty"`
+	CurrentLimit         *float64                 `json:"current_limit,omitempty"`
+	ObstructionDetection *CoverObstructionConfig  `json:"obstruction_detection,omitempty"`
+	Slat                 *CoverSlatConfig         `json:"slat,omitempty"`
+}
+
+type coverMoveParams struct {
+	ID       int      `json:"id"`
+	Duration *float64 `json:"duration,omitempty"`
+}
+
+// CoverPosition is the target of GoToPosition. Pos and Rel move the cover to
+// an absolute or relative position, SlatPos and SlatRel tilt the slats.
+type CoverPosition struct {
+	Pos     *int `json:"pos,omitempty"`
+	Rel     *int `json:"rel,omitempty"`
+	SlatPos *int `json:"slat_pos,omitempty"`
+	SlatRel *int `json:"slat_rel,omitempty"`
+}
+
+type coverPositionParams struct {
+	ID int `json:"id"`
+	CoverPosition
+}
+
+type CoverService struct {
+	Caller rpc.Caller
+}
+
+func NewCoverService(caller rpc.Caller) *CoverService {
+	return &CoverService{
+		Caller: caller,
+	}
+}
+
+// Open opens the cover, duration in seconds is optional.
+func (s *CoverService) Open(ctx context.Context, id int, duration *float64) error {
+	return s.Caller.Call(ctx, "Cover.Open", coverMoveParams{ID: id, Duration: duration}, nil)
+}
+
+// Close closes the cover, duration in seconds is optional.
+func (s *CoverService) Close(ctx context.Context, id int, duration *float64) error {
+	return s.Caller.Call(ctx, "Cover.Close", coverMoveParams{ID: id, Duration: duration}, nil)
+}
+
+func (s *CoverService) Stop(ctx context.Context, id int) error {
+	return s.Caller.Call(ctx, "Cover.Stop", idParams{ID: id}, nil)
+}
+
+// GoToPosition needs a calibrated cover, see CoverStatus.PosControl.
+func (s *CoverService) GoToPosition(ctx context.Context, id int, position CoverPosition) error {
+	return s.Caller.Call(ctx, "Cover.GoToPosition", coverPositionParams{ID: id, CoverPosition: position}, nil)
+}
+
+func (s *CoverService) Calibrate(ctx context.Context, id int) error {
+	return s.Caller.Call(ctx, "Cover.Calibrate", idParams{ID: id}, nil)
+}
+
+func (s *CoverService) GetStatus(ctx context.Context, id int) (*CoverStatus, error) {
+	return call[CoverStatus](ctx, s.Caller, "Cover.GetStatus", idParams{ID: id})
+}
+
+func (s *CoverService) GetConfig(ctx context.Context, id int) (*CoverConfig, error) {
+	return call[CoverConfig](ctx, s.Caller, "Cover.GetConfig", idParams{ID: id})
+}
+
+// SetConfig changes the non nil fields of config, its ID is ignored.
+func (s *CoverService) SetConfig(ctx context.Context, id int, config CoverConfig) (*SetConfigResponse, error) {
+	config.ID = 0
+	return call[SetConfigResponse](ctx, s.Caller, "Cover.SetConfig", setConfigParams{ID: id, Config: config})
+}
@@ -0,0 +1,178 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCoverMove(t *testing.T) {
+	type test struct {
+		title      string
+		method     string
+		wantParams string
+		move       func(*CoverService) error
+	}
+	ctx := context.Background()
+	tests := []test{
+		{
+			title:      "Open",
+			method:     "Cover.Open",
+			wantParams: `{"id":0}`,
+			move:       func(s *CoverService) error { return s.Open(ctx, 0, nil) },
+		},
+		{
+			title:      "Open for a duration",
+			method:     "Cover.Open",
+			wantParams: `{"id":0,"duration":2.5}`,
+			move:       func(s *CoverService) error { return s.Open(ctx, 0, ptr(2.5)) },
+		},
+		{
+			title:      "Close",
+			method:     "Cover.Close",
+			wantParams: `{"id":1}`,
+			move:       func(s *CoverService) error { return s.Close(ctx, 1, nil) },
+		},
+		{
+			title:      "Stop",
+			method:     "Cover.Stop",
+			wantParams: `{"id":0}`,
+			move:       func(s *CoverService) error { return s.Stop(ctx, 0) },
+		},
+		{
+			title:      "Calibrate",
+			method:     "Cover.Calibrate",
+			wantParams: `{"id":0}`,
+			move:       func(s *CoverService) error { return s.Calibrate(ctx, 0) },
+		},
+		{
+			title:      "Go to position",
+			method:     "Cover.GoToPosition",
+			wantParams: `{"id":0,"pos":75}`,
+			move:       func(s *CoverService) error { return s.GoToPosition(ctx, 0, CoverPosition{Pos: ptr(75)}) },
+		},
+		{
+			title:      "Go to relative position and tilt slats",
+			method:     "Cover.GoToPosition",
+			wantParams: `{"id":0,"rel":-10,"slat_pos":0}`,
+			move: func(s *CoverService) error {
+				return s.GoToPosition(ctx, 0, CoverPosition{Rel: ptr(-10), SlatPos: ptr(0)})
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			cl := NewCoverService(SetupCaller(t, tc.method, tc.wantParams, `null`))
+			assert.NoError(t, tc.move(cl))
+		})
+	}
+}
+
+func TestCoverGetStatus(t *testing.T) {
+	cl := NewCoverService(SetupCaller(t, "Cover.GetStatus", `{"id":0}`, fixture("cover_get_status.json")))
+	resp, err := cl.GetStatus(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &CoverStatus{
+		ID:            0,
+		Source:        "limit_switch",
+		State:         CoverOpening,
+		APower:        ptr(85.3),
+		Voltage:       ptr(236.4),
+		Current:       ptr(0.42),
+		PF:            ptr(0.85),
+		Freq:          ptr(50.0),
+		AEnergy:       &Energy{Total: 3.217, ByMinute: []float64{12.5, 0, 0}, MinuteTs: 1654511572},
+		CurrentPos:    ptr(40),
+		TargetPos:     ptr(100),
+		SlatPos:       ptr(50),
+		MoveTimeout:   ptr(36.5),
+		MoveStartedAt: ptr(1654511566.12),
+		PosControl:    true,
+		LastDirection: ptr(CoverDirectionOpen),
+		Temperature:   &Temperature{C: ptr(41.8), F: ptr(107.2)},
+	}, resp)
+	assert.True(t, resp.State.Moving())
+	assert.False(t, resp.HasError(CoverErrorObstruction))
+}
+
+func TestCoverStateMoving(t *testing.T) {
+	tests := map[CoverState]bool{
+		CoverOpen:        false,
+		CoverClosed:      false,
+		CoverOpening:     true,
+		CoverClosing:     true,
+		CoverStopped:     false,
+		CoverCalibrating: true,
+	}
+	for state, want := range tests {
+		t.Run(string(state), func(t *testing.T) {
+			assert.Equal(t, want, state.Moving())
+		})
+	}
+}
+
+func TestCoverGetConfig(t *testing.T) {
+	cl := NewCoverService(SetupCaller(t, "Cover.GetConfig", `{"id":0}`, fixture("cover_get_config.json")))
+	resp, err := cl.GetConfig(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &CoverConfig{
+		Name:             ptr("Living room"),
+		Motor:            &CoverMotorConfig{IdlePowerThr: ptr(2.0), IdleConfirmPeriod: ptr(0.25)},
+		MaxtimeOpen:      ptr(60.0),
+		MaxtimeClose:     ptr(60.0),
+		InitialState:     CoverInitialStopped,
+		InvertDirections: ptr(false),
+		InMode:           CoverInModeDual,
+		SwapInputs:       ptr(false),
+		SafetySwitch: &CoverSafetySwitchConfig{
+			Enable:    ptr(false),
+			Direction: CoverDirectionBoth,
+			Action:    CoverActionStop,
+		},
+		PowerLimit:        ptr(2800.0),
+		VoltageLimit:      ptr(280.0),
+		UndervoltageLimit: ptr(0.0),
+		CurrentLimit:      ptr(10.0),
+		ObstructionDetection: &CoverObstructionConfig{
+			Enable:    ptr(true),
+			Direction: CoverDirectionClose,
+			Action:    CoverActionReverse,
+			PowerThr:  ptr(1000.0),
+			Holdoff:   ptr(1.0),
+		},
+		Slat: &CoverSlatConfig{
+			Enable:     ptr(true),
+			OpenTime:   ptr(1.5),
+			CloseTime:  ptr(1.5),
+			Step:       ptr(20),
+			RetainPos:  ptr(true),
+			PreciseCtl: ptr(false),
+		},
+	}, resp)
+}
+
+func TestCoverSetConfig(t *testing.T) {
+	cl := NewCoverService(SetupCaller(t, "Cover.SetConfig",
+		`{"id":0,"config":{"maxtime_open":45,"obstruction_detection":{"enable":true,"action":"stop"},"slat":{"enable":false}}}`,
+		`{"restart_required":false}`))
+	resp, err := cl.SetConfig(context.Background(), 0, CoverConfig{
+		ID:                   2,
+		MaxtimeOpen:          ptr(45.0),
+		ObstructionDetection: &CoverObstructionConfig{Enable: ptr(true), Action: CoverActionStop},
+		Slat:                 &CoverSlatConfig{Enable: ptr(false)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &SetConfigResponse{RestartRequired: false}, resp)
+}
+
+func TestCoverCallFailure(t *testing.T) {
+	caller := mocks.NewCaller(t)
+	caller.On("Call", mock.Anything, "Cover.GoToPosition", mock.Anything, mock.Anything).Return(&rpc.Error{Code: rpc.CodeFailedPrecondition, Message: "Cover not calibrated!"})
+	cl := NewCoverService(caller)
+	err := cl.GoToPosition(context.Background(), 0, CoverPosition{Pos: ptr(50)})
+	assert.True(t, rpc.IsCode(err, rpc.CodeFailedPrecondition))
+}
@@ -0,0 +1,227 @@
+package components
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+)
+
+// Input modes of LightConfig.InMode on top of the SwitchConfig ones.
+const (
+	InModeDim     = "dim"
+	InModeDualDim = "dual_dim"
+)
+
+// LightTarget is the state a transition fades to, RGB and White are only set
+// by the color components.
+type LightTarget struct {
+	Output     bool    `json:"output"`
+	Brightness float64 `json:"brightness"`
+	RGB        []int   `json:"rgb,omitempty"`
+	White      *int    `json:"white,omitempty"`
+}
+
+// LightTransition is reported while the output fades to Target.
+type LightTransition struct {
+	Target    LightTarget `json:"target"`
+	StartedAt float64     `json:"started_at"`
+	Duration  float64     `json:"duration"`
+}
+
+type LightStatus struct {
+	ID             int              `json:"id"`
+	Source         string           `json:"source"`
+	Output         bool             `json:"output"`
+	Brightness     float64          `json:"brightness"`
+	TimerStartedAt *float64         `json:"timer_started_at,omitempty"`
+	TimerDuration  *float64         `json:"timer_duration,omitempty"`
+	Transition     *LightTransition `json:"transition,omitempty"`
+	APower         *float64         `json:"apower,omitempty"`
+	Voltage        *float64         `json:"voltage,omitempty"`
+	Current        *float64         `json:"current,omitempty"`
+	AEnergy        *Energy          `json:"aenergy,omitempty"`
+	Temperature    *Temperature     `json:"temperature,omitempty"`
+	Errors         []string         `json:"errors,omitempty"`
+}
+
+// HasError tells whether the light reports the given error.
+func (s *LightStatus) HasError(err string) bool {
+	return slices.Contains(s.Errors, err)
+}
+
+type RGBStatus struct {
+	LightStatus
+	RGB []int `json:"rgb"`
+}
+
+type RGBWStatus struct {
+	RGBStatus
+	White int `json:"white"`
+}
+
+// LightNightMode caps the brightness while active, ActiveBetween holds the
+// start and end times as HH:MM.
+type LightNightMode struct {
+	Enable        *bool    `json:"enable,omitempty"`
+	Brightness    *float64 `json:"brightness,omitempty"`
+	ActiveBetween []string `json:"active_between,omitempty"`
+}
+
+// LightConfig fields left nil are not changed by SetConfig.
+type LightConfig struct {
+	ID                    int             `json:"id,omitempty"`
+	Name                  *string         `json:"name,omitempty"`
+	InMode                string          `json:"in_mode,omitempty"`
+	InitialState          string          `json:"initial_state,omitempty"`
+	AutoOn                *bool           `json:"auto_on,omitempty"`
+	AutoOnDelay           *float64        `json:"auto_on_delay,omitempty"`
+	AutoOff               *bool           `json:"auto_off,omitempty"`
+	AutoOffDelay          *float64        `json:"auto_off_delay,omitempty"`
+	TransitionDuration    *float64        `json:"transition_duration,omitempty"`
+	MinBrightnessOnToggle *float64        `json:"min_brightness_on_toggle,omitempty"`
+	NightMode             *LightNightMode `json:"night_mode,omitempty"`
+	ButtonFadeRate        *int            `json:"button_fade_rate,omitempty"`
+	PowerLimit            *float64        `json:"power_limit,omitempty"`
+	VoltageLimit          *float64        `json:"voltage_limit,omitempty"`
+	CurrentLimit          *float64        `json:"current_limit,omitempty"`
+}
+
+// RGBDefault is the color used when the output is turned on without one.
+type RGBDefault struct {
+	Brightness *float64 `json:"brightness,omitempty"`
+	RGB        []int    `json:"rgb,omitempty"`
+}
+
+type RGBConfig struct {
+	LightConfig
+	Default *RGBDefault `json:"default,omitempty"`
+}
+
+type RGBWDefault struct {
+	RGBDefault
+	White *int `json:"white,omitempty"`
+}
+
+type RGBWConfig struct {
+	LightConfig
+	Default *RGBWDefault `json:"default,omitempty"`
+}
+
+// LightSetParams fields left nil keep their current value, ToggleAfter flips
+// the output back after the given seconds.
+type LightSetParams struct {
+	ID                 int      `json:"id"`
+	On                 *bool    `json:"on,omitempty"`
+	Brightness         *float64 `json:"brightness,omitempty"`
+	TransitionDuration *float64 `json:"transition_duration,omitempty"`
+	ToggleAfter        *float64 `json:"toggle_after,omitempty"`
+}
+
+// RGBSetParams sets the color as red, green and blue values from 0 to 255.
+type RGBSetParams struct {
+	LightSetParams
+	RGB []int `json:"rgb,omitempty"`
+}
+
+// RGBWSetParams sets the white channel from 0 to 255 too.
+type RGBWSetParams struct {
+	RGBSetParams
+	White *int `json:"white,omitempty"`
+}
+
+type LightService struct {
+	Caller rpc.Caller
+}
+
+func NewLightService(caller rpc.Caller) *LightService {
+	return &LightService{
+		Caller: caller,
+	}
+}
+
+func (s *LightService) Set(ctx context.Context, params LightSetParams) error {
+	return s.Caller.Call(ctx, "Light.Set", params, nil)
+}
+
+func (s *LightService) Toggle(ctx context.Context, id int) error {
+	return s.Caller.Call(ctx, "Light.Toggle", idParams{ID: id}, nil)
+}
+
+func (s *LightService) GetStatus(ctx context.Context, id int) (*LightStatus, error) {
+	return call[LightStatus](ctx, s.Caller, "Light.GetStatus", idParams{ID: id})
+}
+
+func (s *LightService) GetConfig(ctx context.Context, id int) (*LightConfig, error) {
+	return call[LightConfig](ctx, s.Caller, "Light.GetConfig", idParams{ID: id})
+}
+
+// SetConfig changes the non nil fields of config, its ID is ignored.
+func (s *LightService) SetConfig(ctx context.Context, id int, config LightConfig) (*SetConfigResponse, error) {
+	config.ID = 0
+	return call[SetConfigResponse](ctx, s.Caller, "Light.SetConfig", setConfigParams{ID: id, Config: config})
+}
+
+type RGBService struct {
+	Caller rpc.Caller
+}
+
+func NewRGBService(caller rpc.Caller) *RGBService {
+	return &RGBService{
+		Caller: caller,
+	}
+}
+
+func (s *RGBService) Set(ctx context.Context, params RGBSetParams) error {
+	return s.Caller.Call(ctx, "RGB.Set", params, nil)
+}
+
+func (s *RGBService) Toggle(ctx context.Context, id int) error {
+	return s.Caller.Call(ctx, "RGB.Toggle", idParams{ID: id}, nil)
+}
+
+func (s *RGBService) GetStatus(ctx context.Context, id int) (*RGBStatus, error) {
+	return call[RGBStatus](ctx, s.Caller, "RGB.GetStatus", idParams{ID: id})
+}
+
+func (s *RGBService) GetConfig(ctx context.Context, id int) (*RGBConfig, error) {
+	return call[RGBConfig](ctx, s.Caller, "RGB.GetConfig", idParams{ID: id})
+}
+
+// SetConfig changes the non nil fields of config, its ID is ignored.
+func (s *RGBService) SetConfig(ctx context.Context, id int, config RGBConfig) (*SetConfigResponse, error) {
+	config.ID = 0
+	return call[SetConfigResponse](ctx, s.Caller, "RGB.SetConfig", setConfigParams{ID: id, Config: config})
+}
+
+type RGBWService struct {
+	Caller rpc.Caller
+}
+
+func NewRGBWService(caller rpc.Caller) *RGBWService {
+	return &RGBWService{
+		Caller: caller,
+	}
+}
+
+func (s *RGBWService) Set(ctx context.Context, params RGBWSetParams) error {
+	return s.Caller.Call(ctx, "RGBW.Set", params, nil)
+}
+
+func (s *RGBWService) Toggle(ctx context.Context, id int) error {
+	return s.Caller.Call(ctx, "RGBW.Toggle", idParams{ID: id}, nil)
+}
+
+func (s *RGBWService) GetStatus(ctx context.Context, id int) (*RGBWStatus, error) {
+	return call[RGBWStatus](ctx, s.Caller, "RGBW.GetStatus", idParams{ID: id})
+}
+
+func (s *RGBWService) GetConfig(ctx context.Context, id int) (*RGBWConfig, error) {
+	return call[RGBWConfig](ctx, s.Caller, "RGBW.GetConfig", idParams{ID: id})
+}
+
+// SetConfig changes the non nil fields of config, its ID is ignored.
+func (s *RGBWService) SetConfig(ctx context.Context, id int, config RGBWConfig) (*SetConfigResponse, error) {
+	config.ID = 0
+	return call[SetConfigResponse](ctx, s.Caller, "RGBW.SetConfig", setConfigParams{ID: id, Config: config})
+}
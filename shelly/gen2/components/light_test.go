@@ -0,0 +1,221 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLightSet(t *testing.T) {
+	type test struct {
+		title      string
+		method     string
+		wantParams string
+		set        func(rpc.Caller) error
+	}
+	ctx := context.Background()
+	tests := []test{
+		{
+			title:      "Light brightness with transition",
+			method:     "Light.Set",
+			wantParams: `{"id":0,"on":true,"brightness":60,"transition_duration":2}`,
+			set: func(c rpc.Caller) error {
+				return NewLightService(c).Set(ctx, LightSetParams{ID: 0, On: ptr(true), Brightness: ptr(60.0), TransitionDuration: ptr(2.0)})
+			},
+		},
+		{
+			title:      "Light off with toggle after",
+			method:     "Light.Set",
+			wantParams: `{"id":1,"on":false,"toggle_after":30}`,
+			set: func(c rpc.Caller) error {
+				return NewLightService(c).Set(ctx, LightSetParams{ID: 1, On: ptr(false), ToggleAfter: ptr(30.0)})
+			},
+		},
+		{
+			title:      "RGB color",
+			method:     "RGB.Set",
+			wantParams: `{"id":0,"rgb":[255,0,128]}`,
+			set: func(c rpc.Caller) error {
+				return NewRGBService(c).Set(ctx, RGBSetParams{RGB: []int{255, 0, 128}})
+			},
+		},
+		{
+			title:      "RGBW color and white",
+			method:     "RGBW.Set",
+			wantParams: `{"id":0,"on":true,"brightness":50,"rgb":[0,0,255],"white":200}`,
+			set: func(c rpc.Caller) error {
+				return NewRGBWService(c).Set(ctx, RGBWSetParams{
+					RGBSetParams: RGBSetParams{
+						LightSetParams: LightSetParams{On: ptr(true), Brightness: ptr(50.0)},
+						RGB:            []int{0, 0, 255},
+					},
+					White: ptr(200),
+				})
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			assert.NoError(t, tc.set(SetupCaller(t, tc.method, tc.wantParams, `null`)))
+		})
+	}
+}
+
+func TestLightToggle(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, NewLightService(SetupCaller(t, "Light.Toggle", `{"id":0}`, `null`)).Toggle(ctx, 0))
+	assert.NoError(t, NewRGBService(SetupCaller(t, "RGB.Toggle", `{"id":0}`, `null`)).Toggle(ctx, 0))
+	assert.NoError(t, NewRGBWService(SetupCaller(t, "RGBW.Toggle", `{"id":1}`, `null`)).Toggle(ctx, 1))
+}
+
+func TestLightGetStatus(t *testing.T) {
+	cl := NewLightService(SetupCaller(t, "Light.GetStatus", `{"id":0}`, fixture("light_get_status.json")))
+	resp, err := cl.GetStatus(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &LightStatus{
+		ID:         0,
+		Source:     "button",
+		Output:     true,
+		Brightness: 40,
+		Transition: &LightTransition{
+			Target:    LightTarget{Output: true, Brightness: 80},
+			StartedAt: 1687946540.12,
+			Duration:  2,
+		},
+		APower:      ptr(12.4),
+		Voltage:     ptr(230.1),
+		Current:     ptr(0.07),
+		AEnergy:     &Energy{Total: 1.046, ByMinute: []float64{180.3, 0, 0}, MinuteTs: 1687946580},
+		Temperature: &Temperature{C: ptr(38.6), F: ptr(101.5)},
+	}, resp)
+}
+
+func TestRGBWGetStatus(t *testing.T) {
+	cl := NewRGBWService(SetupCaller(t, "RGBW.GetStatus", `{"id":0}`, fixture("rgbw_get_status.json")))
+	resp, err := cl.GetStatus(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &RGBWStatus{
+		RGBStatus: RGBStatus{
+			LightStatus: LightStatus{
+				ID:             0,
+				Source:         "HTTP_in",
+				Output:         true,
+				Brightness:     100,
+				TimerStartedAt: ptr(1687946540.12),
+				TimerDuration:  ptr(300.0),
+				APower:         ptr(9.2),
+				Voltage:        ptr(12.1),
+				Current:        ptr(0.76),
+				Temperature:    &Temperature{C: ptr(45.1), F: ptr(113.2)},
+				Errors:         []string{SwitchErrorOvertemp},
+			},
+			RGB: []int{255, 120, 0},
+		},
+		White: 64,
+	}, resp)
+	assert.True(t, resp.HasError(SwitchErrorOvertemp))
+}
+
+func TestLightGetConfig(t *testing.T) {
+	cl := NewLightService(SetupCaller(t, "Light.GetConfig", `{"id":0}`, fixture("light_get_config.json")))
+	resp, err := cl.GetConfig(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &LightConfig{
+		Name:                  ptr("Hallway"),
+		InMode:                InModeDim,
+		InitialState:          InitialStateRestoreLast,
+		AutoOn:                ptr(false),
+		AutoOnDelay:           ptr(60.0),
+		AutoOff:               ptr(true),
+		AutoOffDelay:          ptr(600.0),
+		TransitionDuration:    ptr(3.0),
+		MinBrightnessOnToggle: ptr(10.0),
+		NightMode: &LightNightMode{
+			Enable:        ptr(true),
+			Brightness:    ptr(15.0),
+			ActiveBetween: []string{"22:00", "06:00"},
+		},
+		ButtonFadeRate: ptr(3),
+		PowerLimit:     ptr(230.0),
+		VoltageLimit:   ptr(280.0),
+		CurrentLimit:   ptr(1.2),
+	}, resp)
+}
+
+func TestRGBWGetConfig(t *testing.T) {
+	cl := NewRGBWService(SetupCaller(t, "RGBW.GetConfig", `{"id":0}`, fixture("rgbw_get_config.json")))
+	resp, err := cl.GetConfig(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, ptr("Strip"), resp.Name)
+	assert.Equal(t, InModeDetached, resp.InMode)
+	assert.Equal(t, ptr(5.0), resp.MinBrightnessOnToggle)
+	assert.Equal(t, &RGBWDefault{
+		RGBDefault: RGBDefault{Brightness: ptr(100.0), RGB: []int{255, 255, 255}},
+		White:      ptr(128),
+	}, resp.Default)
+}
+
+func TestLightSetConfig(t *testing.T) {
+	type test struct {
+		title      string
+		method     string
+		wantParams string
+		set        func(rpc.Caller) (*SetConfigResponse, error)
+	}
+	ctx := context.Background()
+	tests := []test{
+		{
+			title:      "Light night mode",
+			method:     "Light.SetConfig",
+			wantParams: `{"id":0,"config":{"night_mode":{"enable":true,"brightness":20},"button_fade_rate":5}}`,
+			set: func(c rpc.Caller) (*SetConfigResponse, error) {
+				return NewLightService(c).SetConfig(ctx, 0, LightConfig{
+					ID:             4,
+					NightMode:      &LightNightMode{Enable: ptr(true), Brightness: ptr(20.0)},
+					ButtonFadeRate: ptr(5),
+				})
+			},
+		},
+		{
+			title:      "RGB default color",
+			method:     "RGB.SetConfig",
+			wantParams: `{"id":0,"config":{"initial_state":"on","default":{"rgb":[255,0,0]}}}`,
+			set: func(c rpc.Caller) (*SetConfigResponse, error) {
+				return NewRGBService(c).SetConfig(ctx, 0, RGBConfig{
+					LightConfig: LightConfig{InitialState: InitialStateOn},
+					Default:     &RGBDefault{RGB: []int{255, 0, 0}},
+				})
+			},
+		},
+		{
+			title:      "RGBW min brightness on toggle",
+			method:     "RGBW.SetConfig",
+			wantParams: `{"id":1,"config":{"min_brightness_on_toggle":25}}`,
+			set: func(c rpc.Caller) (*SetConfigResponse, error) {
+				return NewRGBWService(c).SetConfig(ctx, 1, RGBWConfig{
+					LightConfig: LightConfig{ID: 1, MinBrightnessOnToggle: ptr(25.0)},
+				})
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			resp, err := tc.set(SetupCaller(t, tc.method, tc.wantParams, `{"restart_required":false}`))
+			assert.NoError(t, err)
+			assert.Equal(t, &SetConfigResponse{RestartRequired: false}, resp)
+		})
+	}
+}
+
+func TestLightCallFailure(t *testing.T) {
+	caller := mocks.NewCaller(t)
+	caller.On("Call", mock.Anything, "RGB.GetStatus", mock.Anything, mock.Anything).Return(&rpc.Error{Code: rpc.CodeNotFound, Message: "Argument 'id', value 3 not found!"})
+	cl := NewRGBService(caller)
+	resp, err := cl.GetStatus(context.Background(), 3)
+	assert.Nil(t, resp)
+	assert.True(t, rpc.IsCode(err, rpc.CodeNotFound))
+}
@@ -0,0 +1,142 @@
+package components
+
+import (
+	"context"
+	"slices"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+)
+
+// Errors reported in SwitchStatus.Errors.
+const (
+	SwitchErrorOvertemp     = "overtemp"
+	SwitchErrorOverpower    = "overpower"
+	SwitchErrorOvervoltage  = "overvoltage"
+	SwitchErrorUndervoltage = "undervoltage"
+	SwitchErrorOvercurrent  = "overcurrent"
+)
+
+// Input modes of SwitchConfig.InMode.
+const (
+	InModeMomentary = "momentary"
+	InModeFollow    = "follow"
+	InModeFlip      = "flip"
+	InModeDetached  = "detached"
+	InModeCycle     = "cycle"
+	InModeActivate  = "activate"
+)
+
+// Initial states of SwitchConfig.InitialState and LightConfig.InitialState.
+const (
+	InitialStateOff         = "off"
+	InitialStateOn          = "on"
+	InitialStateRestoreLast = "restore_last"
+	InitialStateMatchInput  = "match_input"
+)
+
+// Counters reset by ResetCounters.
+const (
+	CounterAEnergy    = "aenergy"
+	CounterRetAEnergy = "ret_aenergy"
+)
+
+type SwitchStatus struct {
+	ID             int          `json:"id"`
+	Source         string       `json:"source"`
+	Output         bool         `json:"output"`
+	TimerStartedAt *float64     `json:"timer_started_at,omitempty"`
+	TimerDuration  *float64     `json:"timer_duration,omitempty"`
+	APower         *float64     `json:"apower,omitempty"`
+	Voltage        *float64     `json:"voltage,omitempty"`
+	Current        *float64     `json:"current,omitempty"`
+	Freq           *float64     `json:"freq,omitempty"`
+	PF             *float64     `json:"pf,omitempty"`
+	AEnergy        *Energy      `json:"aenergy,omitempty"`
+	RetAEnergy     *Energy      `json:"ret_aenergy,omitempty"`
+	Temperature    *Temperature `json:"temperature,omitempty"`
+	Errors         []string     `json:"errors,omitempty"`
+}
+
+// HasError tells whether the switch reports the given error, e.g.
+// SwitchErrorOvertemp.
+func (s *SwitchStatus) HasError(err string) bool {
+	return slices.Contains(s.Errors, err)
+}
+
+// SwitchConfig fields left nil are not changed by SetConfig.
+type SwitchConfig struct {
+	ID                       int      `json:"id,omitempty"`
+	Name                     *string  `json:"name,omitempty"`
+	InMode                   string   `json:"in_mode,omitempty"`
+	InitialState             string   `json:"initial_state,omitempty"`
+	AutoOn                   *bool    `json:"auto_on,omitempty"`
+	AutoOnDelay              *float64 `json:"auto_on_delay,omitempty"`
+	AutoOff                  *bool    `json:"auto_off,omitempty"`
+	AutoOffDelay             *float64 `json:"auto_off_delay,omitempty"`
+	AutorecoverVoltageErrors *bool    `json:"autorecover_voltage_errors,omitempty"`
+	InputID                  *int     `json:"input_id,omitempty"`
+	PowerLimit               *float64 `json:"power_limit,omitempty"`
+	VoltageLimit             *float64 `json:"voltage_limit,omitempty"`
+	UndervoltageLimit        *float64 `json:"undervoltage_limit,omitempty"`
+	CurrentLimit             *float64 `json:"current_limit,omitempty"`
+}
+
+// SwitchSetParams turns the switch on or off, ToggleAfter flips it back
+// after the given seconds.
+type SwitchSetParams struct {
+	ID          int      `json:"id"`
+	On          bool     `json:"on"`
+	ToggleAfter *float64 `json:"toggle_after,omitempty"`
+}
+
+type SwitchSetResponse struct {
+	WasOn bool `json:"was_on"`
+}
+
+type switchResetParams struct {
+	ID   int      `json:"id"`
+	Type []string `json:"type,omitempty"`
+}
+
+// SwitchCounters holds the counters values before the reset.
+type SwitchCounters struct {
+	AEnergy    *Energy `json:"aenergy,omitempty"`
+	RetAEnergy *Energy `json:"ret_aenergy,omitempty"`
+}
+
+type SwitchService struct {
+	Caller rpc.Caller
+}
+
+func NewSwitchService(caller rpc.Caller) *SwitchService {
+	return &SwitchService{
+		Caller: caller,
+	}
+}
+
+func (s *SwitchService) Set(ctx context.Context, params SwitchSetParams) (*SwitchSetResponse, error) {
+	return call[SwitchSetResponse](ctx, s.Caller, "Switch.Set", params)
+}
+
+func (s *SwitchService) Toggle(ctx context.Context, id int) (*SwitchSetResponse, error) {
+	return call[SwitchSetResponse](ctx, s.Caller, "Switch.Toggle", idParams{ID: id})
+}
+
+func (s *SwitchService) GetStatus(ctx context.Context, id int) (*SwitchStatus, error) {
+	return call[SwitchStatus](ctx, s.Caller, "Switch.GetStatus", idParams{ID: id})
+}
+
+func (s *SwitchService) GetConfig(ctx context.Context, id int) (*SwitchConfig, error) {
+	return call[SwitchConfig](ctx, s.Caller, "Switch.GetConfig", idParams{ID: id})
+}
+
+// SetConfig changes the non nil fields of config, its ID is ignored.
+func (s *SwitchService) SetConfig(ctx context.Context, id int, config SwitchConfig) (*SetConfigResponse, error) {
+	config.ID = 0
+	return call[SetConfigResponse](ctx, s.Caller, "Switch.SetConfig", setConfigParams{ID: id, Config: config})
+}
+
+// ResetCounters resets the given counters, or all of them when none is given.
+func (s *SwitchService) ResetCounters(ctx context.Context, id int, counters ...string) (*SwitchCounters, error) {
+	return call[SwitchCounters](ctx, s.Caller, "Switch.ResetCounters", switchResetParams{ID: id, Type: counters})
+}
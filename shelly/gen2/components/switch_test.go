@@ -0,0 +1,134 @@
+package components
+
+import (
+	"context"
+	"testing"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc"
+	"github.com/rubemlrm/go-shelly/shelly/gen2/rpc/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestSwitchSet(t *testing.T) {
+	type test struct {
+		title      string
+		params     SwitchSetParams
+		wantParams string
+	}
+	tests := []test{
+		{
+			title:      "Turn on",
+			params:     SwitchSetParams{ID: 0, On: true},
+			wantParams: `{"id":0,"on":true}`,
+		},
+		{
+			title:      "Turn off with toggle after",
+			params:     SwitchSetParams{ID: 1, On: false, ToggleAfter: ptr(30.0)},
+			wantParams: `{"id":1,"on":false,"toggle_after":30}`,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			cl := NewSwitchService(SetupCaller(t, "Switch.Set", tc.wantParams, `{"was_on":true}`))
+			resp, err := cl.Set(context.Background(), tc.params)
+			assert.NoError(t, err)
+			assert.Equal(t, &SwitchSetResponse{WasOn: true}, resp)
+		})
+	}
+}
+
+func TestSwitchToggle(t *testing.T) {
+	cl := NewSwitchService(SetupCaller(t, "Switch.Toggle", `{"id":0}`, `{"was_on":false}`))
+	resp, err := cl.Toggle(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &SwitchSetResponse{WasOn: false}, resp)
+}
+
+func TestSwitchGetStatus(t *testing.T) {
+	cl := NewSwitchService(SetupCaller(t, "Switch.GetStatus", `{"id":0}`, fixture("switch_get_status.json")))
+	resp, err := cl.GetStatus(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &SwitchStatus{
+		ID:             0,
+		Source:         "timer",
+		Output:         true,
+		TimerStartedAt: ptr(1626935739.79),
+		TimerDuration:  ptr(60.0),
+		APower:         ptr(8.9),
+		Voltage:        ptr(237.5),
+		Current:        ptr(0.041),
+		Freq:           ptr(50.0),
+		PF:             ptr(0.63),
+		AEnergy:        &Energy{Total: 6.532, ByMinute: []float64{45.199, 47.141, 88.397}, MinuteTs: 1626935779},
+		RetAEnergy:     &Energy{Total: 0.5, ByMinute: []float64{0, 0, 0}, MinuteTs: 1626935779},
+		Temperature:    &Temperature{C: ptr(94.2), F: ptr(201.6)},
+		Errors:         []string{SwitchErrorOvertemp, SwitchErrorOverpower},
+	}, resp)
+	assert.True(t, resp.HasError(SwitchErrorOvertemp))
+	assert.False(t, resp.HasError(SwitchErrorOvervoltage))
+}
+
+func TestSwitchGetConfig(t *testing.T) {
+	cl := NewSwitchService(SetupCaller(t, "Switch.GetConfig", `{"id":0}`, fixture("switch_get_config.json")))
+	resp, err := cl.GetConfig(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Equal(t, &SwitchConfig{
+		Name:                     ptr("Boiler"),
+		InMode:                   InModeFollow,
+		InitialState:             InitialStateMatchInput,
+		AutoOn:                   ptr(false),
+		AutoOnDelay:              ptr(60.0),
+		AutoOff:                  ptr(true),
+		AutoOffDelay:             ptr(3600.0),
+		AutorecoverVoltageErrors: ptr(false),
+		InputID:                  ptr(0),
+		PowerLimit:               ptr(2800.0),
+		VoltageLimit:             ptr(280.0),
+		UndervoltageLimit:        ptr(0.0),
+		CurrentLimit:             ptr(16.0),
+	}, resp)
+}
+
+func TestSwitchSetConfig(t *testing.T) {
+	cl := NewSwitchService(SetupCaller(t, "Switch.SetConfig",
+		`{"id":1,"config":{"name":"Pump","auto_off":false,"initial_state":"restore_last"}}`,
+		`{"restart_required":false}`))
+	resp, err := cl.SetConfig(context.Background(), 1, SwitchConfig{
+		ID:           3,
+		Name:         ptr("Pump"),
+		AutoOff:      ptr(false),
+		InitialState: InitialStateRestoreLast,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, &SetConfigResponse{RestartRequired: false}, resp)
+}
+
+func TestSwitchResetCounters(t *testing.T) {
+	type test struct {
+		title      string
+		counters   []string
+		wantParams string
+	}
+	tests := []test{
+		{title: "All counters", wantParams: `{"id":0}`},
+		{title: "Returned energy only", counters: []string{CounterRetAEnergy}, wantParams: `{"id":0,"type":["ret_aenergy"]}`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			cl := NewSwitchService(SetupCaller(t, "Switch.ResetCounters", tc.wantParams, `{"aenergy":{"total":11.679},"ret_aenergy":{"total":0.5}}`))
+			resp, err := cl.ResetCounters(context.Background(), 0, tc.counters...)
+			assert.NoError(t, err)
+			assert.Equal(t, &SwitchCounters{AEnergy: &Energy{Total: 11.679}, RetAEnergy: &Energy{Total: 0.5}}, resp)
+		})
+	}
+}
+
+func TestSwitchCallFailure(t *testing.T) {
+	caller := mocks.NewCaller(t)
+	caller.On("Call", mock.Anything, "Switch.GetStatus", mock.Anything, mock.Anything).Return(&rpc.Error{Code: rpc.CodeNotFound, Message: "Argument 'id', value 5 not found!"})
+	cl := NewSwitchService(caller)
+	resp, err := cl.GetStatus(context.Background(), 5)
+	assert.Nil(t, resp)
+	assert.True(t, rpc.IsCode(err, rpc.CodeNotFound))
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+const challengeMessage = `{"auth_type": "digest", "nonce": 1625038762, "nc": 1, "realm": "shellypro4pm-f008d1d8b8b8", "algorithm": "SHA-256"}`
+
+func TestParseChallenge(t *testing.T) {
+	type test struct {
+		title string
+		err   error
+		ok    bool
+	}
+	tests := []test{
+		{title: "Unauthorized error", err: &Error{Code: CodeUnauthorized, Message: challengeMessage}, ok: true},
+		{title: "Other error", err: &Error{Code: CodeNotFound, Message: challengeMessage}},
+		{title: "Message without challenge", err: &Error{Code: CodeUnauthorized, Message: "unauthorized"}},
+		{title: "Not an rpc error", err: fmt.Errorf("testing")},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			challenge, ok := ParseChallenge(tc.err)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, &Challenge{AuthType: "digest", Nonce: 1625038762, NC: 1, Realm: "shellypro4pm-f008d1d8b8b8", Algorithm: "SHA-256"}, challenge)
+			}
+		})
+	}
+}
+
+func TestNewAuth(t *testing.T) {
+	challenge, ok := ParseChallenge(&Error{Code: CodeUnauthorized, Message: challengeMessage})
+	assert.True(t, ok)
+
+	auth, err := NewAuth(*challenge, "secret")
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", auth.Username)
+	assert.Equal(t, "shellypro4pm-f008d1d8b8b8", auth.Realm)
+	assert.Equal(t, int64(1625038762), auth.Nonce)
+
+	sum := func(v string) string { return fmt.Sprintf("%x", sha256.Sum256([]byte(v))) }
+	ha1 := sum("admin:shellypro4pm-f008d1d8b8b8:secret")
+	ha2 := sum("dummy_method:dummy_uri")
+	assert.Equal(t, sum(fmt.Sprintf("%s:1625038762:1:%d:auth:%s", ha1, auth.CNonce, ha2)), auth.Response)
+
+	_, err = NewAuth(Challenge{Realm: "x", Algorithm: "MD5"}, "secret")
+	assert.Error(t, err)
+}
+
+func TestFrameAuth(t *testing.T) {
+	auth := NewFrameAuth(transport.StaticCredentials{Password: "secret"}, transport.DeviceIdentity{})
+
+	frame := &Request{ID: 1, Method: "Shelly.GetStatus"}
+	assert.NoError(t, auth.Sign(context.Background(), frame))
+	assert.Nil(t, frame.Auth)
+
+	assert.False(t, auth.Challenged(&Error{Code: CodeInvalidArgument, Message: "bad"}))
+	assert.True(t, auth.Challenged(&Error{Code: CodeUnauthorized, Message: challengeMessage}))
+
+	assert.NoError(t, auth.Sign(context.Background(), frame))
+	if assert.NotNil(t, frame.Auth) {
+		assert.Equal(t, "shellypro4pm-f008d1d8b8b8", frame.Auth.Realm)
+		assert.Equal(t, "SHA-256", frame.Auth.Algorithm)
+	}
+
+	other := NewFrameAuth(transport.StaticCredentials{Username: "user", Password: "secret"}, transport.DeviceIdentity{})
+	assert.True(t, other.Challenged(&Error{Code: CodeUnauthorized, Message: challengeMessage}))
+	assert.ErrorIs(t, other.Sign(context.Background(), &Request{ID: 2}), ErrDigestUsername)
+}
@@ -0,0 +1,221 @@
+package rpc
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	retryablehttp "github.com/hashicorp/go-retryablehttp"
+	"github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+)
+
+// DigestUsername is the only user Gen2 devices accept.
+const DigestUsername = "admin"
+
+// ErrDigestUsername is returned when the credentials name another user than
+// DigestUsername, Gen2 devices would reject them anyway.
+var ErrDigestUsername = errors.New("gen2 devices only accept the " + DigestUsername + " user")
+
+// DigestAuth returns the middleware that answers the HTTP digest challenges
+// of Gen2 devices with the client credentials. Install it with
+// transport.NewRestCustomAuthClient.
+func DigestAuth(client *transport.Client) transport.Middleware {
+	d := &digestAuth{client: client}
+	return d.wrap
+}
+
+// checkUsername rejects credentials for another user than DigestUsername, an
+// empty username stands for it.
+func checkUsername(username string) error {
+	if username != "" && username != DigestUsername {
+		return fmt.Errorf("%w: got %q", ErrDigestUsername, username)
+	}
+	return nil
+}
+
+// digestChallenge is a parsed WWW-Authenticate digest header.
+type digestChallenge struct {
+	realm     string
+	nonce     string
+	opaque    string
+	algorithm string
+	qop       string
+}
+
+// digestAuth answers the device challenges and keeps the last nonce, so the
+// following requests are authorized upfront instead of after a 401.
+type digestAuth struct {
+	client    *transport.Client
+	mu        sync.Mutex
+	challenge *digestChallenge
+	nc        uint32
+}
+
+func (d *digestAuth) wrap(next transport.Handler) transport.Handler {
+	return func(req *retryablehttp.Request) (*http.Response, error) {
+		if req.Request == nil {
+			return next(req)
+		}
+		if challenge, nc := d.next(); challenge != nil {
+			if err := d.authorize(req, challenge, nc); err != nil {
+				return nil, err
+			}
+		}
+		resp, err := next(req)
+		if err != nil || resp.StatusCode != http.StatusUnauthorized {
+			return resp, err
+		}
+		challenge, ok := parseDigestChallenge(resp.Header.Get("WWW-Authenticate"))
+		if !ok {
+			return resp, nil
+		}
+		if resp.Body != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		// The nonce expired or it's the first request, answer the new
+		// challenge once.
+		d.store(challenge)
+		_, nc := d.next()
+		if err := d.authorize(req, challenge, nc); err != nil {
+			return nil, err
+		}
+		return next(req)
+	}
+}
+
+func (d *digestAuth) store(challenge *digestChallenge) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.challenge = challenge
+	d.nc = 0
+}
+
+// next returns the cached challenge and its next nonce count.
+func (d *digestAuth) next() (*digestChallenge, uint32) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.challenge == nil {
+		return nil, 0
+	}
+	d.nc++
+	return d.challenge, d.nc
+}
+
+func (d *digestAuth) authorize(req *retryablehttp.Request, challenge *digestChallenge, nc uint32) error {
+	creds, err := d.client.ResolveCredentials(req.Context())
+	if err != nil {
+		return err
+	}
+	if err := checkUsername(creds.Username); err != nil {
+		return err
+	}
+	h := digestHash(challenge.algorithm)
+	cnonce := randomHex(16)
+	uri := req.URL.RequestURI()
+	ha1 := hashHex(h, creds.Username+":"+challenge.realm+":"+creds.Password.Reveal())
+	ha2 := hashHex(h, req.Method+":"+uri)
+	ncValue := fmt.Sprintf("%08x", nc)
+
+	var response string
+	if challenge.qop == "" {
+		response = hashHex(h, ha1+":"+challenge.nonce+":"+ha2)
+	} else {
+		response = hashHex(h, strings.Join([]string{ha1, challenge.nonce, ncValue, cnonce, "auth", ha2}, ":"))
+	}
+
+	fields := []string{
+		fmt.Sprintf(`username="%s"`, creds.Username),
+		fmt.Sprintf(`realm="%s"`, challenge.realm),
+		fmt.Sprintf(`nonce="%s"`, challenge.nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+	if challenge.algorithm != "" {
+		fields = append(fields, "algorithm="+challenge.algorithm)
+	}
+	if challenge.qop != "" {
+		fields = append(fields, "qop=auth", "nc="+ncValue, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+	if challenge.opaque != "" {
+		fields = append(fields, fmt.Sprintf(`opaque="%s"`, challenge.opaque))
+	}
+	req.Header.Set("Authorization", "Digest "+strings.Join(fields, ", "))
+	return nil
+}
+
+func parseDigestChallenge(header string) (*digestChallenge, bool) {
+	scheme, params, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Digest") {
+		return nil, false
+	}
+	challenge := &digestChallenge{}
+	for _, param := range splitDigestParams(params) {
+		k, v, ok := strings.Cut(param, "=")
+		if !ok {
+			continue
+		}
+		v = strings.Trim(strings.TrimSpace(v), `"`)
+		switch strings.ToLower(strings.TrimSpace(k)) {
+		case "realm":
+			challenge.realm = v
+		case "nonce":
+			challenge.nonce = v
+		case "opaque":
+			challenge.opaque = v
+		case "algorithm":
+			challenge.algorithm = v
+		case "qop":
+			for _, qop := range strings.Split(v, ",") {
+				if strings.TrimSpace(qop) == "auth" {
+					challenge.qop = "auth"
+				}
+			}
+		}
+	}
+	return challenge, challenge.nonce != ""
+}
+
+// splitDigestParams splits on the commas outside quoted values.
+func splitDigestParams(s string) []string {
+	var params []string
+	quoted := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			params = append(params, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(params, s[start:])
+}
+
+func digestHash(algorithm string) func() hash.Hash {
+	if strings.EqualFold(algorithm, "MD5") || algorithm == "" {
+		return md5.New
+	}
+	return sha256.New
+}
+
+func hashHex(h func() hash.Hash, s string) string {
+	sum := h()
+	sum.Write([]byte(s))
+	return hex.EncodeToString(sum.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
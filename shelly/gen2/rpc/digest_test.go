@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+// digestServer checks SHA-256 digest auth the way Gen2 devices do and
+// rotates its nonce when rotate is called.
+type digestServer struct {
+	mu          sync.Mutex
+	nonce       int
+	challenges  int
+	authorized  int
+	password    string
+	realm       string
+	lastNC      string
+	lastURI     string
+	lastHeaders http.Header
+}
+
+func (s *digestServer) rotate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nonce++
+}
+
+func (s *digestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nonce := fmt.Sprintf("%d", s.nonce)
+	if s.check(r, nonce) {
+		s.authorized++
+		fmt.Fprint(w, `{"ok":true}`)
+		return
+	}
+	s.challenges++
+	w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Digest qop="auth", realm="%s", nonce="%s", algorithm=SHA-256`, s.realm, nonce))
+	w.WriteHeader(http.StatusUnauthorized)
+	fmt.Fprint(w, `{"code":401,"message":"unauthorized"}`)
+}
+
+func (s *digestServer) check(r *http.Request, nonce string) bool {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Digest ") {
+		return false
+	}
+	params := map[string]string{}
+	for _, p := range splitDigestParams(strings.TrimPrefix(header, "Digest ")) {
+		k, v, _ := strings.Cut(p, "=")
+		params[strings.TrimSpace(k)] = strings.Trim(v, `"`)
+	}
+	sum := func(v string) string { return fmt.Sprintf("%x", sha256.Sum256([]byte(v))) }
+	ha1 := sum("admin:" + s.realm + ":" + s.password)
+	ha2 := sum(r.Method + ":" + params["uri"])
+	want := sum(strings.Join([]string{ha1, params["nonce"], params["nc"], params["cnonce"], "auth", ha2}, ":"))
+	s.lastNC = params["nc"]
+	s.lastURI = params["uri"]
+	return params["username"] == "admin" && params["nonce"] == nonce && params["response"] == want && params["algorithm"] == "SHA-256"
+}
+
+func newDigestClient(t *testing.T, opts transport.ClientOptions) *transport.Client {
+	if opts.Username == "" {
+		opts.Username = DigestUsername
+	}
+	c, err := transport.NewRestCustomAuthClient(opts, DigestAuth)
+	assert.NoError(t, err)
+	return c
+}
+
+func TestDigestAuth(t *testing.T) {
+	device := &digestServer{password: "secret", realm: "shellyplus1pm-441793d69718"}
+	server := httptest.NewServer(device)
+	t.Cleanup(server.Close)
+
+	c := newDigestClient(t, transport.ClientOptions{Hostname: server.URL, Password: "secret"})
+	call := func() error {
+		req, err := c.NewRequest(http.MethodPost, "/rpc", map[string]interface{}{"id": 1, "method": "Shelly.GetStatus"})
+		assert.NoError(t, err)
+		assert.Empty(t, req.Header.Get("Authorization"))
+		_, err = c.Do(req, &map[string]interface{}{})
+		return err
+	}
+
+	// The first call answers the challenge.
+	assert.NoError(t, call())
+	assert.Equal(t, 1, device.challenges)
+	assert.Equal(t, "00000001", device.lastNC)
+	assert.Equal(t, "/rpc", device.lastURI)
+
+	// The cached nonce authorizes the next call upfront.
+	assert.NoError(t, call())
+	assert.Equal(t, 1, device.challenges)
+	assert.Equal(t, "00000002", device.lastNC)
+
+	// A new nonce makes the client authenticate again.
+	device.rotate()
+	assert.NoError(t, call())
+	assert.Equal(t, 2, device.challenges)
+	assert.Equal(t, "00000001", device.lastNC)
+	assert.Equal(t, 3, device.authorized)
+}
+
+func TestDigestAuthWrongPassword(t *testing.T) {
+	device := &digestServer{password: "secret", realm: "shellyplus1pm-441793d69718"}
+	server := httptest.NewServer(device)
+	t.Cleanup(server.Close)
+
+	c := newDigestClient(t, transport.ClientOptions{Hostname: server.URL, Password: "wrong"})
+	req, err := c.NewRequest(http.MethodGet, "/rpc/Shelly.GetStatus", nil)
+	assert.NoError(t, err)
+	_, err = c.Do(req, &map[string]interface{}{})
+	var statusErr *transport.StatusError
+	assert.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusUnauthorized, statusErr.StatusCode)
+	assert.Equal(t, 2, device.challenges)
+}
+
+func TestDigestAuthOtherUser(t *testing.T) {
+	device := &digestServer{password: "secret", realm: "shellyplus1pm-441793d69718"}
+	server := httptest.NewServer(device)
+	t.Cleanup(server.Close)
+
+	c := newDigestClient(t, transport.ClientOptions{Hostname: server.URL, Username: "user", Password: "secret"})
+	req, err := c.NewRequest(http.MethodGet, "/rpc/Shelly.GetStatus", nil)
+	assert.NoError(t, err)
+	_, err = c.Do(req, &map[string]interface{}{})
+	assert.ErrorIs(t, err, ErrDigestUsername)
+	assert.Equal(t, 1, device.challenges)
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	type test struct {
+		title  string
+		header string
+		want   *digestChallenge
+		ok     bool
+	}
+	tests := []test{
+		{
+			title:  "Gen2 challenge",
+			header: `Digest qop="auth", realm="shellypro4pm-f008d1d8b8b8", nonce="60dc59c6", algorithm=SHA-256`,
+			want:   &digestChallenge{realm: "shellypro4pm-f008d1d8b8b8", nonce: "60dc59c6", algorithm: "SHA-256", qop: "auth"},
+			ok:     true,
+		},
+		{
+			title:  "Quoted commas and several qop values",
+			header: `Digest realm="a,b", qop="auth,auth-int", nonce="abc", opaque="xyz"`,
+			want:   &digestChallenge{realm: "a,b", nonce: "abc", opaque: "xyz", qop: "auth"},
+			ok:     true,
+		},
+		{title: "Basic challenge", header: `Basic realm="shelly"`},
+		{title: "Missing nonce", header: `Digest realm="shelly"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			got, ok := parseDigestChallenge(tc.header)
+			assert.Equal(t, tc.ok, ok)
+			if tc.ok {
+				assert.Equal(t, tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// DefaultSource is the src field of the frames sent by the clients, Gen2
+// devices use it as the destination of their responses.
+const DefaultSource = "go-shelly"
+
+// Error codes returned by Gen2 devices, on top of the JSON-RPC ones.
+const (
+	CodeParseError         = -32700
+	CodeInvalidRequest     = -32600
+	CodeMethodNotFound     = -32601
+	CodeInvalidParams      = -32602
+	CodeInternalError      = -32603
+	CodeInvalidArgument    = -103
+	CodeDeadlineExceeded   = -104
+	CodeNotFound           = -105
+	CodeResourceExhausted  = -106
+	CodeFailedPrecondition = -107
+	CodeUnavailable        = -108
+	CodeUnauthorized       = 401
+	CodeNoHandler          = 404
+)
+
+// ErrIDMismatch is returned when a response doesn't answer the request sent.
+var ErrIDMismatch = errors.New("rpc response id doesn't match the request")
+
+// Caller sends RPC calls to a Gen2 device and decodes the result. Params are
+// marshaled as the params object and result may be nil.
+type Caller interface {
+	Call(ctx context.Context, method string, params, result interface{}) error
+}
+
+// Request is a JSON-RPC request frame.
+type Request struct {
+	JSONRPC string      `json:"jsonrpc,omitempty"`
+	ID      uint64      `json:"id"`
+	Src     string      `json:"src,omitempty"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+	Auth    *Auth       `json:"auth,omitempty"`
+}
+
+// Response is a JSON-RPC response frame, either Result or Error is set.
+type Response struct {
+	ID     uint64          `json:"id"`
+	Src    string          `json:"src,omitempty"`
+	Dst    string          `json:"dst,omitempty"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *Error          `json:"error,omitempty"`
+}
+
+// Error is the error object of a failed call.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rpc error %d: %s", e.Code, e.Message)
+}
+
+// IsCode tells whether err is an RPC error with the given code.
+func IsCode(err error, code int) bool {
+	var rpcErr *Error
+	return errors.As(err, &rpcErr) && rpcErr.Code == code
+}
+
+// decode checks the response frame and decodes its result.
+func (r *Response) decode(id uint64, result interface{}) error {
+	if r.Error != nil {
+		return r.Error
+	}
+	if r.ID != id {
+		return fmt.Errorf("%w: got %d, want %d", ErrIDMismatch, r.ID, id)
+	}
+	if result == nil || len(r.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(r.Result, result)
+}
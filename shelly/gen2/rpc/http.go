@@ -0,0 +1,164 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+	"github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+)
+
+var _ Caller = (*HTTPClient)(nil)
+
+// HTTPClient sends RPC calls over HTTP through a Gen1 transport client, so
+// the retry, logging, limiter and auth options apply to Gen2 devices too.
+type HTTPClient struct {
+	client contracts.ShellyClient
+	// Source is the src field of the frames, defaults to DefaultSource.
+	Source string
+	id     uint64
+}
+
+// NewHTTPClient creates an RPC client on top of a transport client.
+func NewHTTPClient(client contracts.ShellyClient) *HTTPClient {
+	return &HTTPClient{client: client, Source: DefaultSource}
+}
+
+// Call posts a request frame to /rpc and decodes the result.
+func (c *HTTPClient) Call(ctx context.Context, method string, params, result interface{}) error {
+	req := c.NewRequest(method, params)
+	frame, _, err := c.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return frame.decode(req.ID, result)
+}
+
+// NewRequest builds a request frame with the next id.
+func (c *HTTPClient) NewRequest(method string, params interface{}) *Request {
+	return &Request{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&c.id, 1),
+		Src:     c.Source,
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// Do posts a request frame to /rpc. The response frame is returned along
+// with the HTTP response metadata, an error frame is returned as *Error.
+// Calls aren't retried, most RPC methods aren't safe to send twice.
+func (c *HTTPClient) Do(ctx context.Context, frame *Request) (*Response, *contracts.Response, error) {
+	req, err := c.client.NewRequestWithContext(callContext(ctx), http.MethodPost, "/rpc", transport.Params{Encoding: transport.EncodingJSON, Values: frame})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out Response
+	resp, err := c.client.Do(req, &out)
+	if err != nil {
+		var statusErr *transport.StatusError
+		if errors.As(err, &statusErr) {
+			if json.Unmarshal(statusErr.Body, &out) == nil && out.Error != nil {
+				return nil, resp, out.Error
+			}
+			if rpcErr := parseError(statusErr.Body); rpcErr != nil {
+				return nil, resp, rpcErr
+			}
+		}
+		return nil, resp, err
+	}
+	if out.Error != nil {
+		return nil, resp, out.Error
+	}
+	if out.ID != frame.ID {
+		return nil, resp, fmt.Errorf("%w: got %d, want %d", ErrIDMismatch, out.ID, frame.ID)
+	}
+	return &out, resp, nil
+}
+
+// CallGet sends the call with the GET /rpc/<method> form. Every top level
+// param is sent JSON encoded in the query, e.g. id=0&on=true.
+func (c *HTTPClient) CallGet(ctx context.Context, method string, params, result interface{}) error {
+	values, err := queryParams(params)
+	if err != nil {
+		return err
+	}
+	req, err := c.client.NewRequestWithContext(callContext(ctx), http.MethodGet, "/rpc/"+url.PathEscape(method), values)
+	if err != nil {
+		return err
+	}
+
+	var out json.RawMessage
+	resp, err := c.client.Do(req, &out)
+	if err != nil {
+		var statusErr *transport.StatusError
+		if errors.As(err, &statusErr) {
+			if rpcErr := parseError(statusErr.Body); rpcErr != nil {
+				return rpcErr
+			}
+		}
+		return err
+	}
+	if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+		if rpcErr := parseError(out); rpcErr != nil {
+			return rpcErr
+		}
+		return fmt.Errorf("rpc call %s failed with status %d", method, resp.StatusCode)
+	}
+	if result == nil || len(out) == 0 {
+		return nil
+	}
+	return json.Unmarshal(out, result)
+}
+
+// callContext disables the transport retries and keeps the error replies of
+// the device from tripping its breaker.
+func callContext(ctx context.Context) context.Context {
+	return transport.WithApplicationErrors(transport.WithoutRetry(ctx), isErrorReply)
+}
+
+// isErrorReply tells if body is an error frame, or the bare error object of
+// the GET form.
+func isErrorReply(body []byte) bool {
+	var frame Response
+	if json.Unmarshal(body, &frame) == nil && frame.Error != nil {
+		return true
+	}
+	return parseError(body) != nil
+}
+
+func queryParams(params interface{}) (url.Values, error) {
+	if params == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(params)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, fmt.Errorf("rpc params must be an object: %w", err)
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	values := make(url.Values, len(fields))
+	for k, v := range fields {
+		values.Set(k, string(v))
+	}
+	return values, nil
+}
+
+func parseError(body []byte) *Error {
+	var rpcErr Error
+	if err := json.Unmarshal(body, &rpcErr); err != nil || (rpcErr.Code == 0 && rpcErr.Message == "") {
+		return nil
+	}
+	return &rpcErr
+}
@@ -0,0 +1,216 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+	"github.com/stretchr/testify/assert"
+)
+
+func SetupHTTPClient(t *testing.T) (*http.ServeMux, *HTTPClient) {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := transport.NewRestClient(transport.ClientOptions{Hostname: server.URL})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	return mux, NewHTTPClient(client)
+}
+
+type switchStatus struct {
+	ID     int  `json:"id"`
+	Output bool `json:"output"`
+}
+
+func TestHTTPClientCall(t *testing.T) {
+	type test struct {
+		title    string
+		response func(req Request) string
+		want     *switchStatus
+		wantCode int
+		wantErr  error
+	}
+	tests := []test{
+		{
+			title: "Result is decoded",
+			response: func(req Request) string {
+				return fmt.Sprintf(`{"id":%d,"src":"shellyplus1-441793d69718","dst":"go-shelly","result":{"id":0,"output":true}}`, req.ID)
+			},
+			want: &switchStatus{ID: 0, Output: true},
+		},
+		{
+			title: "Error frame is returned as Error",
+			response: func(req Request) string {
+				return fmt.Sprintf(`{"id":%d,"src":"shellyplus1-441793d69718","error":{"code":-105,"message":"Argument 'id', value 3 not found!"}}`, req.ID)
+			},
+			wantCode: CodeNotFound,
+		},
+		{
+			title: "Response to another request",
+			response: func(req Request) string {
+				return fmt.Sprintf(`{"id":%d,"result":{}}`, req.ID+1)
+			},
+			wantErr: ErrIDMismatch,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			mux, client := SetupHTTPClient(t)
+			mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method)
+				assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+				var req Request
+				assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, "2.0", req.JSONRPC)
+				assert.Equal(t, DefaultSource, req.Src)
+				assert.Equal(t, "Switch.GetStatus", req.Method)
+				assert.Equal(t, map[string]interface{}{"id": float64(0)}, req.Params)
+				fmt.Fprint(w, tc.response(req))
+			})
+
+			var got switchStatus
+			err := client.Call(context.Background(), "Switch.GetStatus", map[string]int{"id": 0}, &got)
+			switch {
+			case tc.wantCode != 0:
+				assert.True(t, IsCode(err, tc.wantCode))
+			case tc.wantErr != nil:
+				assert.ErrorIs(t, err, tc.wantErr)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, tc.want, &got)
+			}
+		})
+	}
+}
+
+func TestHTTPClientRequestIDs(t *testing.T) {
+	client := NewHTTPClient(nil)
+	first := client.NewRequest("Shelly.GetStatus", nil)
+	second := client.NewRequest("Shelly.GetStatus", nil)
+	assert.Equal(t, first.ID+1, second.ID)
+}
+
+func TestHTTPClientCallGet(t *testing.T) {
+	type test struct {
+		title     string
+		params    interface{}
+		status    int
+		body      string
+		wantQuery map[string]string
+		wantCode  int
+	}
+	tests := []test{
+		{
+			title: "Params are JSON encoded in the query",
+			params: struct {
+				ID   int    `json:"id"`
+				On   bool   `json:"on"`
+				Name string `json:"name"`
+			}{ID: 0, On: true, Name: "kitchen"},
+			status:    http.StatusOK,
+			body:      `{"was_on":false}`,
+			wantQuery: map[string]string{"id": "0", "on": "true", "name": `"kitchen"`},
+		},
+		{
+			title:    "Missing handler",
+			status:   http.StatusNotFound,
+			body:     `{"code":404,"message":"No handler for Switch.Foo"}`,
+			wantCode: CodeNoHandler,
+		},
+		{
+			title:    "Invalid argument",
+			status:   http.StatusBadRequest,
+			body:     `{"code":-103,"message":"Missing required argument 'id'!"}`,
+			wantCode: CodeInvalidArgument,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			mux, client := SetupHTTPClient(t)
+			mux.HandleFunc("/rpc/Switch.Set", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodGet, r.Method)
+				for k, v := range tc.wantQuery {
+					assert.Equal(t, v, r.URL.Query().Get(k))
+				}
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			})
+
+			var got struct {
+				WasOn bool `json:"was_on"`
+			}
+			err := client.CallGet(context.Background(), "Switch.Set", tc.params, &got)
+			if tc.wantCode != 0 {
+				assert.True(t, IsCode(err, tc.wantCode), "unexpected error %v", err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestHTTPClientUnauthorized(t *testing.T) {
+	mux, client := SetupHTTPClient(t)
+	mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"code":401,"message":"unauthorized"}`)
+	})
+	err := client.Call(context.Background(), "Shelly.GetStatus", nil, nil)
+	assert.True(t, IsCode(err, CodeUnauthorized))
+}
+
+func TestHTTPClientServerError(t *testing.T) {
+	type test struct {
+		title     string
+		body      string
+		wantState transport.BreakerState
+	}
+	tests := []test{
+		{
+			title:     "Error frame keeps the breaker closed",
+			body:      `{"id":%d,"error":{"code":-114,"message":"Method not supported"}}`,
+			wantState: transport.BreakerClosed,
+		},
+		{
+			title:     "Broken reply trips the breaker",
+			body:      `internal error %d`,
+			wantState: transport.BreakerOpen,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			mux := http.NewServeMux()
+			server := httptest.NewServer(mux)
+			t.Cleanup(server.Close)
+			client, err := transport.NewRestClient(transport.ClientOptions{
+				Hostname: server.URL,
+				Breaker:  &transport.BreakerOptions{FailureThreshold: 1, OpenTimeout: time.Hour, ProbeInterval: time.Hour},
+			})
+			assert.NoError(t, err)
+			t.Cleanup(client.Close)
+
+			hits := 0
+			mux.HandleFunc("/rpc", func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				var req Request
+				json.NewDecoder(r.Body).Decode(&req)
+				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Fprintf(w, tc.body, req.ID)
+			})
+			err = NewHTTPClient(client).Call(context.Background(), "Cover.Open", nil, nil)
+			assert.Error(t, err)
+			assert.Equal(t, 1, hits)
+			assert.Equal(t, tc.wantState, client.BreakerState())
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+	"github.com/rubemlrm/go-shelly/shelly/gen1/transport"
+)
+
+// Notification methods sent by Gen2 devices over WebSocket.
+const (
+	NotifyStatus     = "NotifyStatus"
+	NotifyFullStatus = "NotifyFullStatus"
+	NotifyEvent      = "NotifyEvent"
+)
+
+var (
+	// ErrDisconnected is returned by calls sent while the channel is down or
+	// waiting for a response when the connection dropped.
+	ErrDisconnected = errors.New("websocket disconnected")
+	// ErrClosed is returned by calls sent after Close.
+	ErrClosed = errors.New("websocket client closed")
+)
+
+var _ Caller = (*WSClient)(nil)
+
+// WSOptions configures a WebSocket RPC client.
+type WSOptions struct {
+	// Hostname is the device address, see transport.ParseAddress.
+	Hostname string
+	// Password enables the frame auth, the username is always admin.
+	Password contracts.Secret
+	// Credentials replaces Password with a provider.
+	Credentials transport.CredentialProvider
+	// MAC identifies the device for per device credential providers.
+	MAC string
+	// Source is the src field of the frames, defaults to DefaultSource.
+	// Devices send their notifications to it.
+	Source string
+	// Dialer defaults to websocket.DefaultDialer.
+	Dialer *websocket.Dialer
+	// MinBackoff and MaxBackoff bound the wait between reconnections.
+	// They default to 500 milliseconds and 30 seconds.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// Buffer is the size of every subscription channel. Defaults to 16.
+	Buffer int
+	// Logger receives the connection errors and reconnections.
+	Logger *slog.Logger
+}
+
+// Event is an entry of a NotifyEvent notification.
+type Event struct {
+	Component string          `json:"component"`
+	ID        int             `json:"id"`
+	Event     string          `json:"event"`
+	Timestamp float64         `json:"ts"`
+	Data      json.RawMessage `json:"data,omitempty"`
+}
+
+// Notification is an unsolicited frame sent by the device. Status holds the
+// changed components of NotifyStatus, or all of them for NotifyFullStatus,
+// keyed by component such as switch:0.
+type Notification struct {
+	Src       string
+	Method    string
+	Timestamp time.Time
+	Status    map[string]json.RawMessage
+	Events    []Event
+}
+
+type wsFrame struct {
+	Response
+	Method string          `json:"method,omitempty"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type subscriber struct {
+	methods map[string]bool
+	ch      chan Notification
+}
+
+// WSClient is a persistent RPC channel to ws://<device>/rpc. It correlates
+// responses by id, reconnects with backoff when the connection drops and
+// delivers the notifications to the subscribers.
+type WSClient struct {
+	url     string
+	opts    WSOptions
+	auth    *FrameAuth
+	logger  *slog.Logger
+	id      uint64
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	conn    *websocket.Conn
+	pending map[uint64]chan *Response
+	subs    map[*subscriber]struct{}
+	closed  bool
+	done    chan struct{}
+}
+
+// DialWS connects to the device and keeps the connection up until Close.
+func DialWS(ctx context.Context, opts WSOptions) (*WSClient, error) {
+	address, err := transport.ParseAddress(opts.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	u := address.URL()
+	u.Scheme = "ws"
+	if address.Scheme == "https" {
+		u.Scheme = "wss"
+	}
+	u.Path += "/rpc"
+
+	if opts.Source == "" {
+		opts.Source = DefaultSource
+	}
+	if opts.Dialer == nil {
+		opts.Dialer = websocket.DefaultDialer
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 500 * time.Millisecond
+	}
+	if opts.MaxBackoff < opts.MinBackoff {
+		opts.MaxBackoff = max(30*time.Second, opts.MinBackoff)
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 16
+	}
+	c := &WSClient{
+		url:     u.String(),
+		opts:    opts,
+		logger:  opts.Logger,
+		pending: map[uint64]chan *Response{},
+		subs:    map[*subscriber]struct{}{},
+		done:    make(chan struct{}),
+	}
+	if c.logger == nil {
+		c.logger = transport.DiscardLogger()
+	}
+	if opts.Credentials != nil || opts.Password != "" {
+		var provider transport.CredentialProvider = transport.StaticCredentials{Password: opts.Password}
+		if opts.Credentials != nil {
+			provider = opts.Credentials
+		}
+		c.auth = NewFrameAuth(provider, transport.DeviceIdentity{Host: u.Hostname(), MAC: opts.MAC})
+	}
+
+	conn, _, err := opts.Dialer.DialContext(ctx, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	go c.run(conn)
+	go c.hello()
+	return c, nil
+}
+
+// Call sends a request frame and waits for its response. A 401 answer is
+// retried once with the challenge it carries.
+func (c *WSClient) Call(ctx context.Context, method string, params, result interface{}) error {
+	err := c.call(ctx, method, params, result)
+	if err != nil && c.auth != nil && c.auth.Challenged(err) {
+		err = c.call(ctx, method, params, result)
+	}
+	return err
+}
+
+func (c *WSClient) call(ctx context.Context, method string, params, result interface{}) error {
+	frame := &Request{
+		JSONRPC: "2.0",
+		ID:      atomic.AddUint64(&c.id, 1),
+		Src:     c.opts.Source,
+		Method:  method,
+		Params:  params,
+	}
+	if c.auth != nil {
+		if err := c.auth.Sign(ctx, frame); err != nil {
+			return err
+		}
+	}
+	payload, err := json.Marshal(frame)
+	if err != nil {
+		return err
+	}
+
+	ch := make(chan *Response, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClosed
+	}
+	conn := c.conn
+	if conn == nil {
+		c.mu.Unlock()
+		return ErrDisconnected
+	}
+	c.pending[frame.ID] = ch
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, frame.ID)
+		c.mu.Unlock()
+	}()
+
+	c.writeMu.Lock()
+	err = conn.WriteMessage(websocket.TextMessage, payload)
+	c.writeMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDisconnected, err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return ErrDisconnected
+		}
+		return resp.decode(frame.ID, result)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Subscribe returns the notifications with the given methods, or all of
+// them when none is given. Notifications are dropped while the channel is
+// full. The returned func cancels the subscription.
+func (c *WSClient) Subscribe(methods ...string) (<-chan Notification, func()) {
+	sub := &subscriber{methods: map[string]bool{}, ch: make(chan Notification, c.opts.Buffer)}
+	for _, m := range methods {
+		sub.methods[m] = true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		close(sub.ch)
+		return sub.ch, func() {}
+	}
+	c.subs[sub] = struct{}{}
+	return sub.ch, func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if _, ok := c.subs[sub]; ok {
+			delete(c.subs, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Close stops the reconnections, closes the connection and the
+// subscriptions.
+func (c *WSClient) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	close(c.done)
+	conn := c.conn
+	c.conn = nil
+	for sub := range c.subs {
+		delete(c.subs, sub)
+		close(sub.ch)
+	}
+	c.mu.Unlock()
+	if conn == nil {
+		return nil
+	}
+	return conn.Close()
+}
+
+// hello sends a first call so the device learns the source and starts
+// sending notifications to it.
+func (c *WSClient) hello() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := c.Call(ctx, "Shelly.GetDeviceInfo", nil, nil); err != nil {
+		c.logger.Warn("websocket hello failed", slog.String("url", c.url), slog.Any("error", err))
+	}
+}
+
+func (c *WSClient) run(conn *websocket.Conn) {
+	for {
+		err := c.read(conn)
+		c.mu.Lock()
+		closed := c.closed
+		c.conn = nil
+		for id, ch := range c.pending {
+			delete(c.pending, id)
+			close(ch)
+		}
+		c.mu.Unlock()
+		if closed {
+			return
+		}
+		c.logger.Warn("websocket disconnected", slog.String("url", c.url), slog.Any("error", err))
+		conn.Close()
+
+		if conn = c.reconnect(); conn == nil {
+			return
+		}
+		go c.hello()
+	}
+}
+
+func (c *WSClient) reconnect() *websocket.Conn {
+	for attempt := 0; ; attempt++ {
+		wait := time.Duration(float64(c.opts.MinBackoff) * math.Pow(2, float64(attempt)))
+		if wait <= 0 || wait > c.opts.MaxBackoff {
+			wait = c.opts.MaxBackoff
+		}
+		select {
+		case <-c.done:
+			return nil
+		case <-time.After(wait):
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), c.opts.MaxBackoff)
+		conn, _, err := c.opts.Dialer.DialContext(ctx, c.url, nil)
+		cancel()
+		if err != nil {
+			c.logger.Debug("websocket reconnection failed", slog.String("url", c.url), slog.Int("attempt", attempt+1), slog.Any("error", err))
+			continue
+		}
+		c.mu.Lock()
+		if c.closed {
+			c.mu.Unlock()
+			conn.Close()
+			return nil
+		}
+		c.conn = conn
+		c.mu.Unlock()
+		c.logger.Info("websocket reconnected", slog.String("url", c.url))
+		return conn
+	}
+}
+
+func (c *WSClient) read(conn *websocket.Conn) error {
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			return err
+		}
+		var frame wsFrame
+		if err := json.Unmarshal(payload, &frame); err != nil {
+			continue
+		}
+		if frame.Method != "" {
+			c.notify(frame)
+			continue
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[frame.ID]
+		c.mu.Unlock()
+		if ok {
+			resp := frame.Response
+			select {
+			case ch <- &resp:
+			default:
+			}
+		}
+	}
+}
+
+func (c *WSClient) notify(frame wsFrame) {
+	n := Notification{Src: frame.Src, Method: frame.Method}
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(frame.Params, &params); err != nil {
+		return
+	}
+	if raw, ok := params["ts"]; ok {
+		var ts float64
+		if json.Unmarshal(raw, &ts) == nil {
+			sec, frac := math.Modf(ts)
+			n.Timestamp = time.Unix(int64(sec), int64(frac*1e9))
+		}
+		delete(params, "ts")
+	}
+	switch frame.Method {
+	case NotifyEvent:
+		if err := json.Unmarshal(params["events"], &n.Events); err != nil {
+			return
+		}
+	case NotifyStatus, NotifyFullStatus:
+		n.Status = params
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for sub := range c.subs {
+		if len(sub.methods) > 0 && !sub.methods[n.Method] {
+			continue
+		}
+		select {
+		case sub.ch <- n:
+		default:
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	contracts "github.com/rubemlrm/go-shelly/shelly/gen1/contracts"
+	"github.com/stretchr/testify/assert"
+)
+
+// wsDevice is a fake Gen2 device. It answers the calls, sends a
+// NotifyStatus and a NotifyEvent after the first call of every connection
+// and can drop the connection on demand.
+type wsDevice struct {
+	t           *testing.T
+	password    string
+	connections int32
+	mu          sync.Mutex
+	conn        *websocket.Conn
+}
+
+func (d *wsDevice) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	assert.Equal(d.t, "/rpc", r.URL.Path)
+	conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	atomic.AddInt32(&d.connections, 1)
+	d.mu.Lock()
+	d.conn = conn
+	d.mu.Unlock()
+
+	notified := false
+	for {
+		var req Request
+		if err := conn.ReadJSON(&req); err != nil {
+			return
+		}
+		resp := map[string]interface{}{"id": req.ID, "src": "shellyplus1pm-441793d69718", "dst": req.Src}
+		switch {
+		case d.password != "" && !d.authorized(req.Auth):
+			challenge := `{"auth_type":"digest","nonce":1625038762,"nc":1,"realm":"shellyplus1pm-441793d69718","algorithm":"SHA-256"}`
+			resp["error"] = map[string]interface{}{"code": 401, "message": challenge}
+		case req.Method == "Switch.GetStatus":
+			resp["result"] = map[string]interface{}{"id": 0, "output": true}
+		default:
+			resp["result"] = map[string]interface{}{}
+		}
+		d.mu.Lock()
+		conn.WriteJSON(resp)
+		if !notified && resp["error"] == nil {
+			notified = true
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"src":"shellyplus1pm-441793d69718","dst":"go-shelly","method":"NotifyStatus","params":{"ts":1631266595.5,"switch:0":{"id":0,"output":true}}}`))
+			conn.WriteMessage(websocket.TextMessage, []byte(`{"src":"shellyplus1pm-441793d69718","dst":"go-shelly","method":"NotifyEvent","params":{"ts":1631266595.5,"events":[{"component":"input:0","id":0,"event":"single_push","ts":1631266595.5}]}}`))
+		}
+		d.mu.Unlock()
+	}
+}
+
+func (d *wsDevice) authorized(auth *Auth) bool {
+	if auth == nil {
+		return false
+	}
+	sum := func(v string) string { return fmt.Sprintf("%x", sha256.Sum256([]byte(v))) }
+	ha1 := sum("admin:" + auth.Realm + ":" + d.password)
+	ha2 := sum("dummy_method:dummy_uri")
+	return auth.Response == sum(fmt.Sprintf("%s:%d:1:%d:auth:%s", ha1, auth.Nonce, auth.CNonce, ha2))
+}
+
+func (d *wsDevice) drop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.conn.Close()
+}
+
+func setupWSDevice(t *testing.T, password string) (*wsDevice, string) {
+	device := &wsDevice{t: t, password: password}
+	server := httptest.NewServer(device)
+	t.Cleanup(server.Close)
+	return device, server.URL
+}
+
+func receiveNotification(t *testing.T, ch <-chan Notification) Notification {
+	select {
+	case n := <-ch:
+		return n
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a notification")
+	}
+	return Notification{}
+}
+
+func TestWSOptionsFormattingRedactsPassword(t *testing.T) {
+	opts := WSOptions{Hostname: "192.168.1.50", Password: "hunter2"}
+	for _, format := range []string{"%v", "%+v", "%#v"} {
+		assert.NotContains(t, fmt.Sprintf(format, opts), "hunter2")
+	}
+}
+
+func TestWSClientCall(t *testing.T) {
+	type test struct {
+		title    string
+		password string
+		client   contracts.Secret
+	}
+	tests := []test{
+		{title: "Device without auth"},
+		{title: "Device with auth", password: "secret", client: "secret"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			_, addr := setupWSDevice(t, tc.password)
+			c, err := DialWS(context.Background(), WSOptions{Hostname: addr, Password: tc.client})
+			assert.NoError(t, err)
+			defer c.Close()
+
+			var got switchStatus
+			assert.NoError(t, c.Call(context.Background(), "Switch.GetStatus", map[string]int{"id": 0}, &got))
+			assert.Equal(t, switchStatus{ID: 0, Output: true}, got)
+		})
+	}
+}
+
+func TestWSClientWrongPassword(t *testing.T) {
+	_, addr := setupWSDevice(t, "secret")
+	c, err := DialWS(context.Background(), WSOptions{Hostname: addr, Password: "wrong"})
+	assert.NoError(t, err)
+	defer c.Close()
+
+	err = c.Call(context.Background(), "Switch.GetStatus", nil, nil)
+	assert.True(t, IsCode(err, CodeUnauthorized))
+}
+
+func TestWSClientNotifications(t *testing.T) {
+	_, addr := setupWSDevice(t, "")
+	c, err := DialWS(context.Background(), WSOptions{Hostname: addr})
+	assert.NoError(t, err)
+	statuses, _ := c.Subscribe(NotifyStatus)
+	events, unsubscribe := c.Subscribe(NotifyEvent)
+	defer unsubscribe()
+
+	status := receiveNotification(t, statuses)
+	assert.Equal(t, NotifyStatus, status.Method)
+	assert.Equal(t, "shellyplus1pm-441793d69718", status.Src)
+	assert.Equal(t, time.Unix(1631266595, 5e8), status.Timestamp)
+	assert.JSONEq(t, `{"id":0,"output":true}`, string(status.Status["switch:0"]))
+	assert.NotContains(t, status.Status, "ts")
+
+	event := receiveNotification(t, events)
+	assert.Equal(t, []Event{{Component: "input:0", ID: 0, Event: "single_push", Timestamp: 1631266595.5}}, event.Events)
+
+	assert.NoError(t, c.Close())
+	_, ok := <-statuses
+	assert.False(t, ok)
+	assert.ErrorIs(t, c.Call(context.Background(), "Shelly.GetStatus", nil, nil), ErrClosed)
+}
+
+func TestWSClientReconnect(t *testing.T) {
+	device, addr := setupWSDevice(t, "")
+	c, err := DialWS(context.Background(), WSOptions{Hostname: addr, MinBackoff: 10 * time.Millisecond})
+	assert.NoError(t, err)
+	defer c.Close()
+	statuses, _ := c.Subscribe(NotifyStatus)
+	receiveNotification(t, statuses)
+
+	device.drop()
+	// The hello call of the new connection brings a new notification.
+	receiveNotification(t, statuses)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&device.connections))
+
+	var got json.RawMessage
+	assert.NoError(t, c.Call(context.Background(), "Switch.GetStatus", map[string]int{"id": 0}, &got))
+	assert.JSONEq(t, `{"id":0,"output":true}`, string(got))
+}
@@ -0,0 +1,78 @@
+package models
+
+// known holds the Gen1 type codes and Gen2 app names this library knows.
+var known = []Model{
+	// Gen1 relays and plugs
+	{Code: "SHSW-1", Name: "Shelly 1", Generation: 1, Relays: 1, Inputs: 1},
+	{Code: "SHSW-PM", Name: "Shelly 1PM", Generation: 1, Relays: 1, Meters: 1, Inputs: 1},
+	{Code: "SHSW-L", Name: "Shelly 1L", Generation: 1, Relays: 1, Meters: 1, Inputs: 2},
+	{Code: "SHSW-21", Name: "Shelly 2", Generation: 1, Relays: 2, Rollers: 1, Meters: 1, Inputs: 2},
+	{Code: "SHSW-25", Name: "Shelly 2.5", Generation: 1, Relays: 2, Rollers: 1, Meters: 2, Inputs: 2},
+	{Code: "SHSW-22", Name: "Shelly HD", Generation: 1, Relays: 2, Meters: 2, Inputs: 2},
+	{Code: "SHSW-44", Name: "Shelly 4Pro", Generation: 1, Relays: 4, Meters: 4, Inputs: 4},
+	{Code: "SHAIR-1", Name: "Shelly Air", Generation: 1, Relays: 1, Meters: 1, Inputs: 1},
+	{Code: "SHPLG-1", Name: "Shelly Plug", Generation: 1, Relays: 1, Meters: 1},
+	{Code: "SHPLG2-1", Name: "Shelly Plug", Generation: 1, Relays: 1, Meters: 1},
+	{Code: "SHPLG-S", Name: "Shelly Plug S", Generation: 1, Relays: 1, Meters: 1},
+	{Code: "SHPLG-U1", Name: "Shelly Plug US", Generation: 1, Relays: 1, Meters: 1},
+	{Code: "SHUNI-1", Name: "Shelly UNI", Generation: 1, Relays: 2, Inputs: 2, Power: PowerDC, Endpoints: []string{"/adc"}},
+	{Code: "SHIX3-1", Name: "Shelly i3", Generation: 1, Inputs: 3},
+
+	// Gen1 energy meters
+	{Code: "SHEM", Name: "Shelly EM", Generation: 1, Relays: 1, EMeters: 2},
+	{Code: "SHEM-3", Name: "Shelly 3EM", Generation: 1, Relays: 1, EMeters: 3},
+
+	// Gen1 lights
+	{Code: "SHDM-1", Name: "Shelly Dimmer", Generation: 1, Lights: 1, Meters: 1, Inputs: 2},
+	{Code: "SHDM-2", Name: "Shelly Dimmer 2", Generation: 1, Lights: 1, Meters: 1, Inputs: 2},
+	{Code: "SHDIMW-1", Name: "Shelly Dimmer W1", Generation: 1, Lights: 1, Meters: 1, Inputs: 2},
+	{Code: "SHRGBW2", Name: "Shelly RGBW2", Generation: 1, Lights: 4, Colors: 1, Whites: 4, Meters: 4, Inputs: 1, Power: PowerDC},
+	{Code: "SHBLB-1", Name: "Shelly Bulb", Generation: 1, Lights: 1, Colors: 1, Whites: 1, Meters: 1},
+	{Code: "SHCB-1", Name: "Shelly Color Bulb", Generation: 1, Lights: 1, Colors: 1, Whites: 1, Meters: 1},
+	{Code: "SHBDUO-1", Name: "Shelly Duo", Generation: 1, Lights: 1, Meters: 1},
+	{Code: "SHVIN-1", Name: "Shelly Vintage", Generation: 1, Lights: 1, Meters: 1},
+
+	// Gen1 sensors
+	{Code: "SHHT-1", Name: "Shelly H&T", Generation: 1, Power: PowerBatteryOrUSB},
+	{Code: "SHWT-1", Name: "Shelly Flood", Generation: 1, Power: PowerBattery},
+	{Code: "SHDW-1", Name: "Shelly Door/Window", Generation: 1, Power: PowerBattery},
+	{Code: "SHDW-2", Name: "Shelly Door/Window 2", Generation: 1, Power: PowerBattery},
+	{Code: "SHSEN-1", Name: "Shelly Sense", Generation: 1, Power: PowerBatteryOrUSB, Endpoints: []string{"/ir"}},
+	{Code: "SHGS-1", Name: "Shelly Gas", Generation: 1, Endpoints: []string{"/self_test", "/mute", "/unmute"}},
+	{Code: "SHSM-01", Name: "Shelly Smoke", Generation: 1, Power: PowerBattery},
+	{Code: "SHMOS-01", Name: "Shelly Motion", Generation: 1, Power: PowerBatteryOrUSB},
+	{Code: "SHMOS-02", Name: "Shelly Motion 2", Generation: 1, Power: PowerBatteryOrUSB},
+	{Code: "SHBTN-1", Name: "Shelly Button1", Generation: 1, Inputs: 1, Power: PowerBatteryOrUSB},
+	{Code: "SHBTN-2", Name: "Shelly Button1 v2", Generation: 1, Inputs: 1, Power: PowerBatteryOrUSB},
+	{Code: "SHTRV-01", Name: "Shelly TRV", Generation: 1, Power: PowerBatteryOrUSB, Endpoints: []string{"/thermostat"}},
+
+	// Gen2 Plus
+	{Code: "Plus1", Name: "Shelly Plus 1", Generation: 2, Relays: 1, Inputs: 1, Endpoints: []string{"Switch", "Input"}},
+	{Code: "Plus1PM", Name: "Shelly Plus 1PM", Generation: 2, Relays: 1, Meters: 1, Inputs: 1, Endpoints: []string{"Switch", "Input"}},
+	{Code: "Plus2PM", Name: "Shelly Plus 2PM", Generation: 2, Relays: 2, Rollers: 1, Meters: 2, Inputs: 2, Endpoints: []string{"Switch", "Cover", "Input"}},
+	{Code: "PlusI4", Name: "Shelly Plus i4", Generation: 2, Inputs: 4, Endpoints: []string{"Input"}},
+	{Code: "PlusPlugS", Name: "Shelly Plus Plug S", Generation: 2, Relays: 1, Meters: 1, Endpoints: []string{"Switch", "PLUGS_UI"}},
+	{Code: "PlusPlugUS", Name: "Shelly Plus Plug US", Generation: 2, Relays: 1, Meters: 1, Endpoints: []string{"Switch"}},
+	{Code: "PlusPlugIT", Name: "Shelly Plus Plug IT", Generation: 2, Relays: 1, Meters: 1, Endpoints: []string{"Switch"}},
+	{Code: "PlusPlugUK", Name: "Shelly Plus Plug UK", Generation: 2, Relays: 1, Meters: 1, Endpoints: []string{"Switch"}},
+	{Code: "PlusHT", Name: "Shelly Plus H&T", Generation: 2, Power: PowerBatteryOrUSB, Endpoints: []string{"Temperature", "Humidity", "DevicePower", "HT_UI"}},
+	{Code: "PlusWallDimmer", Name: "Shelly Plus Wall Dimmer", Generation: 2, Lights: 1, Endpoints: []string{"Light"}},
+	{Code: "PlusRGBWPM", Name: "Shelly Plus RGBW PM", Generation: 2, Lights: 4, Meters: 4, Inputs: 4, Power: PowerDC, Endpoints: []string{"Light", "RGB", "RGBW", "Input"}},
+
+	// Gen2 Mini
+	{Code: "Mini1", Name: "Shelly Plus 1 Mini", Generation: 2, Relays: 1, Inputs: 1, Endpoints: []string{"Switch", "Input"}},
+	{Code: "Mini1PM", Name: "Shelly Plus 1PM Mini", Generation: 2, Relays: 1, Meters: 1, Inputs: 1, Endpoints: []string{"Switch", "Input"}},
+	{Code: "MiniPM", Name: "Shelly Plus PM Mini", Generation: 2, Meters: 1, Endpoints: []string{"PM1"}},
+
+	// Gen2 Pro
+	{Code: "Pro1", Name: "Shelly Pro 1", Generation: 2, Relays: 1, Inputs: 2, Endpoints: []string{"Switch", "Input", "Eth"}},
+	{Code: "Pro1PM", Name: "Shelly Pro 1PM", Generation: 2, Relays: 1, Meters: 1, Inputs: 2, Endpoints: []string{"Switch", "Input", "Eth"}},
+	{Code: "Pro2", Name: "Shelly Pro 2", Generation: 2, Relays: 2, Inputs: 2, Endpoints: []string{"Switch", "Input", "Eth"}},
+	{Code: "Pro2PM", Name: "Shelly Pro 2PM", Generation: 2, Relays: 2, Rollers: 1, Meters: 2, Inputs: 2, Endpoints: []string{"Switch", "Cover", "Input", "Eth"}},
+	{Code: "Pro3", Name: "Shelly Pro 3", Generation: 2, Relays: 3, Inputs: 3, Endpoints: []string{"Switch", "Input", "Eth"}},
+	{Code: "Pro4PM", Name: "Shelly Pro 4PM", Generation: 2, Relays: 4, Meters: 4, Inputs: 4, Endpoints: []string{"Switch", "Input", "Eth", "UI"}},
+	{Code: "ProEM", Name: "Shelly Pro EM", Generation: 2, Relays: 1, EMeters: 2, Endpoints: []string{"Switch", "EM1", "EM1Data", "Eth"}},
+	{Code: "Pro3EM", Name: "Shelly Pro 3EM", Generation: 2, EMeters: 3, Endpoints: []string{"EM", "EMData", "Eth"}},
+	{Code: "ProDimmerx1", Name: "Shelly Pro Dimmer 1PM", Generation: 2, Lights: 1, Meters: 1, Inputs: 2, Endpoints: []string{"Light", "Input", "Eth"}},
+	{Code: "ProDimmerx2", Name: "Shelly Pro Dimmer 2PM", Generation: 2, Lights: 2, Meters: 2, Inputs: 4, Endpoints: []string{"Light", "Input", "Eth"}},
+}
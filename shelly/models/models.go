@@ -0,0 +1,179 @@
+package models
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnsupportedEndpoint is returned by services when the device model
+// doesn't expose the requested endpoint.
+var ErrUnsupportedEndpoint = errors.New("endpoint not supported by device model")
+
+// PowerSource is how a device is powered.
+type PowerSource int
+
+const (
+	PowerMains PowerSource = iota
+	PowerBattery
+	// PowerBatteryOrUSB devices run on batteries but stay awake when powered
+	// over USB.
+	PowerBatteryOrUSB
+	// PowerDC devices are powered by a 12-24V supply.
+	PowerDC
+)
+
+func (p PowerSource) String() string {
+	switch p {
+	case PowerMains:
+		return "mains"
+	case PowerBattery:
+		return "battery"
+	case PowerBatteryOrUSB:
+		return "battery or usb"
+	case PowerDC:
+		return "dc"
+	}
+	return "unknown"
+}
+
+// Model describes a device model and the channels it exposes. Gen1 models
+// are keyed by the type code of /shelly, like SHSW-25, Gen2 models by their
+// app name, like Plus1PM.
+type Model struct {
+	Code       string
+	Name       string
+	Generation int
+	Relays     int
+	Rollers    int
+	Lights     int
+	// Colors and Whites count the Gen1 /color and /white channels of the
+	// color and white modes.
+	Colors  int
+	Whites  int
+	Meters  int
+	EMeters int
+	Inputs  int
+	Power   PowerSource
+	// Endpoints lists the extra Gen1 endpoints, like /thermostat, or the
+	// Gen2 RPC components, like Switch, exposed by the model.
+	Endpoints []string
+}
+
+// gen1Common are the endpoints every Gen1 device exposes.
+var gen1Common = []string{"shelly", "settings", "status", "reboot", "ota", "wifiscan", "cit"}
+
+// gen2Common are the RPC components every Gen2 device exposes.
+var gen2Common = []string{"Shelly", "Sys", "WiFi", "Cloud", "MQTT", "BLE", "Ws", "Script", "Schedule", "Webhook", "KVS", "HTTP"}
+
+// Supports tells if the model exposes endpoint. Gen1 endpoints are paths
+// like /relay/1 or /settings/roller/0, Gen2 endpoints are RPC methods like
+// Switch.Set. Channel endpoints are checked against the channel count.
+func (m Model) Supports(endpoint string) bool {
+	if m.Generation >= 2 {
+		// RPC method names are case insensitive, /shelly is the Shelly component.
+		component := strings.SplitN(strings.TrimPrefix(strings.TrimPrefix(endpoint, "/rpc"), "/"), ".", 2)[0]
+		match := func(c string) bool { return strings.EqualFold(c, component) }
+		return slices.ContainsFunc(gen2Common, match) || slices.ContainsFunc(m.Endpoints, match)
+	}
+
+	segments := strings.Split(strings.Trim(endpoint, "/"), "/")
+	if segments[0] == "settings" && len(segments) > 1 {
+		if count, ok := m.channels(segments[1]); ok {
+			return channelSupported(count, segments[2:])
+		}
+		return true
+	}
+	if count, ok := m.channels(segments[0]); ok {
+		return channelSupported(count, segments[1:])
+	}
+	if slices.Contains(gen1Common, segments[0]) {
+		return true
+	}
+	return slices.ContainsFunc(m.Endpoints, func(e string) bool {
+		return strings.Trim(e, "/") == segments[0]
+	})
+}
+
+// channels returns the channel count behind a Gen1 endpoint kind.
+func (m Model) channels(kind string) (int, bool) {
+	switch kind {
+	case "relay":
+		return m.Relays, true
+	case "roller":
+		return m.Rollers, true
+	case "light":
+		return m.Lights, true
+	case "color":
+		return m.Colors, true
+	case "white":
+		return m.Whites, true
+	case "meter":
+		return m.Meters, true
+	case "emeter":
+		return m.EMeters, true
+	case "input":
+		return m.Inputs, true
+	}
+	return 0, false
+}
+
+func channelSupported(count int, rest []string) bool {
+	if count == 0 {
+		return false
+	}
+	if len(rest) == 0 || rest[0] == "" {
+		return true
+	}
+	index, err := strconv.Atoi(rest[0])
+	return err == nil && index >= 0 && index < count
+}
+
+// clone copies the model so callers can't change the registered one.
+func (m Model) clone() Model {
+	m.Endpoints = slices.Clone(m.Endpoints)
+	return m
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Model{}
+)
+
+func init() {
+	for _, m := range known {
+		registry[m.Code] = m
+	}
+}
+
+// Lookup returns the model registered under code.
+func Lookup(code string) (Model, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	m, ok := registry[code]
+	return m.clone(), ok
+}
+
+// Register adds or replaces a model, so new devices can be described before
+// the registry catches up.
+func Register(m Model) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[m.Code] = m.clone()
+}
+
+// All returns every registered model sorted by code.
+func All() []Model {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	all := make([]Model, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m.clone())
+	}
+	slices.SortFunc(all, func(a, b Model) int {
+		return strings.Compare(a.Code, b.Code)
+	})
+	return all
+}
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLookup(t *testing.T) {
+	type test struct {
+		title  string
+		code   string
+		want   string
+		wantOk bool
+	}
+	tests := []test{
+		{title: "Gen1 type code", code: "SHSW-21", want: "Shelly 2", wantOk: true},
+		{title: "Gen1 energy meter", code: "SHEM-3", want: "Shelly 3EM", wantOk: true},
+		{title: "Gen2 app name", code: "Plus1PM", want: "Shelly Plus 1PM", wantOk: true},
+		{title: "Unknown code", code: "SHXX-99", wantOk: false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			m, ok := Lookup(tc.code)
+			assert.Equal(t, tc.wantOk, ok)
+			assert.Equal(t, tc.want, m.Name)
+		})
+	}
+}
+
+func TestLookupReturnsCopy(t *testing.T) {
+	m, ok := Lookup("SHTRV-01")
+	assert.True(t, ok)
+	want := slices.Clone(m.Endpoints)
+	m.Endpoints[0] = "changed"
+	_ = append(m.Endpoints[:0], "appended")
+
+	m, _ = Lookup("SHTRV-01")
+	assert.Equal(t, want, m.Endpoints)
+}
+
+func TestSupports(t *testing.T) {
+	type test struct {
+		title    string
+		code     string
+		endpoint string
+		want     bool
+	}
+	tests := []test{
+		{title: "Common endpoint", code: "SHHT-1", endpoint: "/settings", want: true},
+		{title: "Common nested endpoint", code: "SHHT-1", endpoint: "/ota/check", want: true},
+		{title: "Relay in range", code: "SHSW-25", endpoint: "/relay/1", want: true},
+		{title: "Relay out of range", code: "SHSW-25", endpoint: "/relay/2", want: false},
+		{title: "Roller on relay only model", code: "SHSW-1", endpoint: "/roller/0", want: false},
+		{title: "Roller settings", code: "SHSW-25", endpoint: "/settings/roller/0", want: true},
+		{title: "Relay settings out of range", code: "SHSW-1", endpoint: "/settings/relay/1", want: false},
+		{title: "Other settings endpoint", code: "SHSW-1", endpoint: "/settings/ap", want: true},
+		{title: "Color channel on rgbw", code: "SHRGBW2", endpoint: "/color/0", want: true},
+		{title: "Color channel out of range on rgbw", code: "SHRGBW2", endpoint: "/color/1", want: false},
+		{title: "White channel on rgbw", code: "SHRGBW2", endpoint: "/white/3", want: true},
+		{title: "White channel on color bulb", code: "SHCB-1", endpoint: "/white/0", want: true},
+		{title: "Color channel on dimmer", code: "SHDM-2", endpoint: "/color/0", want: false},
+		{title: "Emeter on em", code: "SHEM", endpoint: "/emeter/1", want: true},
+		{title: "Emeter on plug", code: "SHPLG-S", endpoint: "/emeter/0", want: false},
+		{title: "Model specific endpoint", code: "SHTRV-01", endpoint: "/thermostat/0", want: true},
+		{title: "Unknown endpoint", code: "SHSW-1", endpoint: "/thermostat/0", want: false},
+		{title: "Gen2 common component", code: "Plus1", endpoint: "Shelly.GetStatus", want: true},
+		{title: "Gen2 component", code: "Plus2PM", endpoint: "/rpc/Cover.Open", want: true},
+		{title: "Gen2 missing component", code: "Plus1", endpoint: "Cover.Open", want: false},
+		{title: "Gen2 component in any case", code: "Plus1", endpoint: "switch.set", want: true},
+		{title: "Gen2 shelly endpoint", code: "Plus1", endpoint: "/shelly", want: true},
+		{title: "Gen2 rpc path", code: "Plus2PM", endpoint: "/rpc/Cover.GetStatus", want: true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.title, func(t *testing.T) {
+			m, ok := Lookup(tc.code)
+			assert.True(t, ok)
+			assert.Equal(t, tc.want, m.Supports(tc.endpoint))
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	Register(Model{Code: "SHTEST-1", Name: "Shelly Test", Generation: 1, Relays: 1})
+	m, ok := Lookup("SHTEST-1")
+	assert.True(t, ok)
+	assert.Equal(t, "Shelly Test", m.Name)
+	assert.Contains(t, All(), m)
+}
+
+func TestRegistryConsistency(t *testing.T) {
+	seen := map[string]bool{}
+	for _, m := range known {
+		assert.False(t, seen[m.Code], "duplicated code %s", m.Code)
+		seen[m.Code] = true
+		assert.NotEmpty(t, m.Name)
+		assert.Contains(t, []int{1, 2}, m.Generation)
+	}
+}
+
+func TestPowerSourceString(t *testing.T) {
+	assert.Equal(t, "mains", PowerMains.String())
+	assert.Equal(t, "battery", PowerBattery.String())
+	assert.Equal(t, "battery or usb", PowerBatteryOrUSB.String())
+	assert.Equal(t, "dc", PowerDC.String())
+}